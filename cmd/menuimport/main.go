@@ -0,0 +1,79 @@
+// Command menuimport imports or exports menus, categories and foods in the same
+// CSV/JSON format as POST /menus/import and GET /menus/export.
+//
+//	menuimport -file menus.csv -dry-run
+//	menuimport -file menus.json
+//	menuimport -export -menu <menu_id> -format csv > menus.csv
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	controllers "golang-Restaurant-Management-backend/controllers"
+	"golang-Restaurant-Management-backend/menuio"
+)
+
+func main() {
+	file := flag.String("file", "", "file to import (or to export to, default stdout)")
+	format := flag.String("format", "", "csv or json (default: from the file extension, else json)")
+	dryRun := flag.Bool("dry-run", false, "validate the file and report what would change without writing")
+	export := flag.Bool("export", false, "export menus instead of importing")
+	menuId := flag.String("menu", "", "only export this menu_id")
+	flag.Parse()
+
+	if *format == "" {
+		*format = menuio.FormatJSON
+		if strings.EqualFold(filepath.Ext(*file), ".csv") {
+			*format = menuio.FormatCSV
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	if *export {
+		rows, err := controllers.ExportMenuRows(ctx, *menuId)
+		if err != nil {
+			log.Fatal(err)
+		}
+		out := os.Stdout
+		if *file != "" {
+			if out, err = os.Create(*file); err != nil {
+				log.Fatal(err)
+			}
+			defer out.Close()
+		}
+		if err := menuio.Write(out, *format, rows); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *file == "" {
+		log.Fatal("-file is required")
+	}
+	in, err := os.Open(*file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rows, parseErrs := menuio.Read(in, *format)
+	in.Close()
+
+	result, err := controllers.ImportMenuRows(ctx, nil, rows, parseErrs, *dryRun)
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(result.Errors) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,34 @@
+// Command setrole gives a user a role, for deployments that need a manager and
+// have none who could grant it through PATCH /users/:user_id/role.
+//
+//	setrole -email owner@example.com
+//	setrole -email cook@example.com -role STAFF
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	controllers "golang-Restaurant-Management-backend/controllers"
+	"golang-Restaurant-Management-backend/models"
+)
+
+func main() {
+	email := flag.String("email", "", "email of the user")
+	role := flag.String("role", models.UserRoleManager, "STAFF or MANAGER")
+	flag.Parse()
+
+	if *email == "" {
+		log.Fatal("-email is required")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if err := controllers.SetRoleByEmail(ctx, *email, *role); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("%s is now %s", *email, *role)
+}
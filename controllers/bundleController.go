@@ -0,0 +1,273 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"golang-Restaurant-Management-backend/database"
+	"golang-Restaurant-Management-backend/models"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var bundleCollection *mongo.Collection = database.OpenCollection(database.Client, "bundle")
+
+func GetBundles() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		// Optionally narrow the list down to one menu.
+		filter := bson.M{}
+		if menuId := c.Query("menu_id"); menuId != "" {
+			filter["menu_id"] = menuId
+		}
+
+		result, err := bundleCollection.Find(ctx, filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while listing bundles"})
+			return
+		}
+
+		var allBundles []bson.M
+		if err = result.All(ctx, &allBundles); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, allBundles)
+	}
+}
+
+func GetBundle() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		bundleId := c.Param("bundle_id")
+		var bundle models.Bundle
+
+		err := bundleCollection.FindOne(ctx, bson.M{"bundle_id": bundleId}).Decode(&bundle)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while fetching the bundle"})
+			return
+		}
+
+		c.JSON(http.StatusOK, bundle)
+	}
+}
+
+func CreateBundle() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var menu models.Menu
+		var bundle models.Bundle
+
+		// Bind and validate the incoming JSON data.
+		if err := c.BindJSON(&bundle); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		validationErr := validate.Struct(bundle)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		// The bundle must belong to an existing menu and only offer existing foods.
+		err := menuCollection.FindOne(ctx, bson.M{"menu_id": bundle.Menu_id}).Decode(&menu)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Menu was not found"})
+			return
+		}
+		if err := checkBundleSlots(ctx, bundle.Slots); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		bundle.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		bundle.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		bundle.ID = primitive.NewObjectID()
+		bundle.Bundle_id = bundle.ID.Hex()
+
+		var num = toFixed(*bundle.Price, 2)
+		bundle.Price = &num
+
+		result, insertErr := bundleCollection.InsertOne(ctx, bundle)
+		if insertErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Bundle was not created"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+func UpdateBundle() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var bundle models.Bundle
+		bundleId := c.Param("bundle_id")
+
+		if err := c.BindJSON(&bundle); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var updateObj primitive.D
+
+		// Append updated fields to the update object.
+		if bundle.Name != nil {
+			updateObj = append(updateObj, bson.E{"name", bundle.Name})
+		}
+		if bundle.Price != nil {
+			var num = toFixed(*bundle.Price, 2)
+			updateObj = append(updateObj, bson.E{"price", num})
+		}
+		if bundle.Food_image != nil {
+			updateObj = append(updateObj, bson.E{"food_image", bundle.Food_image})
+		}
+		if bundle.Slots != nil {
+			if validationErr := validate.Var(bundle.Slots, "min=1,dive"); validationErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+				return
+			}
+			if err := checkBundleSlots(ctx, bundle.Slots); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			updateObj = append(updateObj, bson.E{"slots", bundle.Slots})
+		}
+
+		bundle.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		updateObj = append(updateObj, bson.E{"updated_at", bundle.Updated_at})
+
+		result, err := bundleCollection.UpdateOne(
+			ctx,
+			bson.M{"bundle_id": bundleId},
+			bson.D{
+				{"$set", updateObj},
+			},
+			options.Update(),
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Bundle update failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// checks that slot names are unique and every option points at an existing food.
+func checkBundleSlots(ctx context.Context, slots []models.BundleSlot) error {
+	seen := map[string]bool{}
+	for _, slot := range slots {
+		if seen[slot.Name] {
+			return fmt.Errorf("slot %q is defined twice", slot.Name)
+		}
+		seen[slot.Name] = true
+
+		for _, option := range slot.Options {
+			count, err := foodCollection.CountDocuments(ctx, bson.M{"food_id": option.Food_id})
+			if err != nil {
+				return err
+			}
+			if count == 0 {
+				return fmt.Errorf("food %s in slot %q was not found", option.Food_id, slot.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// expands an ordered bundle into the bundle line and one component line per slot.
+// The bundle line carries the bundle price and each component carries its upcharge,
+// so summing the lines of an order gives the right total.
+func expandBundle(ctx context.Context, orderItem models.OrderItem) ([]models.OrderItem, error) {
+	var bundle models.Bundle
+	err := bundleCollection.FindOne(ctx, bson.M{"bundle_id": orderItem.Bundle_id}).Decode(&bundle)
+	if err != nil {
+		return nil, fmt.Errorf("bundle %s was not found", *orderItem.Bundle_id)
+	}
+
+	selected := map[string]string{}
+	for _, selection := range orderItem.Selections {
+		selected[selection.Slot] = selection.Food_id
+	}
+
+	menuVersionId := publishedMenuVersion(ctx, *bundle.Menu_id)
+
+	parent := orderItem
+	parent.Menu_version_id = menuVersionId
+	parent.ID = primitive.NewObjectID()
+	parent.Order_item_id = parent.ID.Hex()
+	parent.Item_type = models.OrderItemTypeBundle
+	parent.Food_id = nil
+	price := toFixed(*bundle.Price, 2)
+	parent.Unit_price = &price
+
+	items := []models.OrderItem{parent}
+	for _, slot := range bundle.Slots {
+		foodId, ok := selected[slot.Name]
+		if !ok && len(slot.Options) == 1 {
+			// Nothing to choose from, e.g. a fixed course of a tasting menu.
+			foodId, ok = slot.Options[0].Food_id, true
+		}
+		if !ok {
+			return nil, fmt.Errorf("no selection for slot %q of bundle %s", slot.Name, *bundle.Name)
+		}
+
+		option, found := bundleOption(slot, foodId)
+		if !found {
+			return nil, fmt.Errorf("food %s is not an option for slot %q", foodId, slot.Name)
+		}
+		delete(selected, slot.Name)
+
+		component := models.OrderItem{
+			Quantity:             orderItem.Quantity,
+			Food_id:              &option.Food_id,
+			Item_type:            models.OrderItemTypeComponent,
+			Parent_order_item_id: &parent.Order_item_id,
+			Menu_version_id:      menuVersionId,
+			Course:               orderItem.Course,
+			Order_id:             orderItem.Order_id,
+		}
+		if slot.Course != nil {
+			component.Course = slot.Course
+		}
+		component.ID = primitive.NewObjectID()
+		component.Order_item_id = component.ID.Hex()
+		upcharge := toFixed(option.Upcharge, 2)
+		component.Unit_price = &upcharge
+		var food models.Food
+		if err := foodCollection.FindOne(ctx, bson.M{"food_id": option.Food_id}).Decode(&food); err == nil {
+			if food.Off_menu {
+				return nil, fmt.Errorf("%s is not on the menu", stringValue(food.Name))
+			}
+			component.Nutrition = food.Nutrition
+			component.Station_id = routeStation(ctx, food, nil)
+		}
+		items = append(items, component)
+	}
+
+	for slot := range selected {
+		return nil, fmt.Errorf("bundle %s has no slot %q", *bundle.Name, slot)
+	}
+	return items, nil
+}
+
+func bundleOption(slot models.BundleSlot, foodId string) (models.BundleOption, bool) {
+	for _, option := range slot.Options {
+		if option.Food_id == foodId {
+			return option, true
+		}
+	}
+	return models.BundleOption{}, false
+}
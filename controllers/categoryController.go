@@ -0,0 +1,430 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"golang-Restaurant-Management-backend/database"
+	"golang-Restaurant-Management-backend/models"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CategoryNode is a category with its foods and subcategories, in display order.
+type CategoryNode struct {
+	Category_id  string                        `json:"category_id"`
+	Name         string                        `json:"name"`
+	Translations map[string]models.Translation `json:"-"`
+	Position     int                           `json:"position"`
+	Foods        []models.Food                 `json:"foods"`
+	Children     []*CategoryNode               `json:"children"`
+}
+
+// MenuTree is a menu laid out for rendering. Foods without a category are listed separately.
+type MenuTree struct {
+	Menu          models.Menu     `json:"menu"`
+	Categories    []*CategoryNode `json:"categories"`
+	Uncategorized []models.Food   `json:"uncategorized"`
+}
+
+// PositionUpdate is one entry of a bulk reorder request. Parent_id (categories) and
+// Category_id (foods) are optional and move the entry to another parent at the same time.
+type PositionUpdate struct {
+	Category_id *string `json:"category_id"`
+	Food_id     string  `json:"food_id"`
+	Parent_id   *string `json:"parent_id"`
+	Position    *int    `json:"position" validate:"required,gte=0"`
+}
+
+var categoryCollection *mongo.Collection = database.OpenCollection(database.Client, "category")
+
+var displayOrder = options.Find().SetSort(bson.D{{"position", 1}, {"name", 1}})
+
+func GetCategories() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		filter := bson.M{}
+		if menuId := c.Query("menu_id"); menuId != "" {
+			filter["menu_id"] = menuId
+		}
+
+		result, err := categoryCollection.Find(ctx, filter, displayOrder)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while listing categories"})
+			return
+		}
+
+		var allCategories []bson.M
+		if err = result.All(ctx, &allCategories); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, allCategories)
+	}
+}
+
+func GetCategory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		categoryId := c.Param("category_id")
+		var category models.Category
+
+		err := categoryCollection.FindOne(ctx, bson.M{"category_id": categoryId}).Decode(&category)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while fetching the category"})
+			return
+		}
+
+		c.JSON(http.StatusOK, category)
+	}
+}
+
+func CreateCategory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var category models.Category
+
+		if err := c.BindJSON(&category); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		validationErr := validate.Struct(category)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		count, err := menuCollection.CountDocuments(ctx, bson.M{"menu_id": category.Menu_id})
+		if err != nil || count == 0 {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Menu was not found"})
+			return
+		}
+		if category.Parent_id != nil {
+			if err := checkCategoryInMenu(ctx, *category.Parent_id, *category.Menu_id); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		// New categories go last among their siblings unless a position is given.
+		if category.Position == nil {
+			count, _ := categoryCollection.CountDocuments(ctx, bson.M{"menu_id": category.Menu_id, "parent_id": category.Parent_id})
+			position := int(count)
+			category.Position = &position
+		}
+
+		category.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		category.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		category.ID = primitive.NewObjectID()
+		category.Category_id = category.ID.Hex()
+
+		result, insertErr := categoryCollection.InsertOne(ctx, category)
+		if insertErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Category was not created"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+func UpdateCategory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var update models.Category
+		var category models.Category
+		categoryId := c.Param("category_id")
+
+		if err := c.BindJSON(&update); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		err := categoryCollection.FindOne(ctx, bson.M{"category_id": categoryId}).Decode(&category)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Category was not found"})
+			return
+		}
+
+		var updateObj primitive.D
+
+		if update.Name != nil {
+			updateObj = append(updateObj, bson.E{"name", update.Name})
+		}
+		if update.Position != nil {
+			if *update.Position < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "position must not be negative"})
+				return
+			}
+			updateObj = append(updateObj, bson.E{"position", update.Position})
+		}
+		if update.Parent_id != nil {
+			// An empty parent_id moves the category to the top level.
+			if *update.Parent_id == "" {
+				updateObj = append(updateObj, bson.E{"parent_id", nil})
+			} else {
+				if err := checkCategoryParent(ctx, category, *update.Parent_id); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+				updateObj = append(updateObj, bson.E{"parent_id", update.Parent_id})
+			}
+		}
+
+		update.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		updateObj = append(updateObj, bson.E{"updated_at", update.Updated_at})
+
+		result, err := categoryCollection.UpdateOne(
+			ctx,
+			bson.M{"category_id": categoryId},
+			bson.D{
+				{"$set", updateObj},
+			},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Category update failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// ReorderCategories sets the position (and optionally the parent) of many categories at once.
+func ReorderCategories() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var updates []PositionUpdate
+
+		if err := c.BindJSON(&updates); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		writes := []mongo.WriteModel{}
+		for _, update := range updates {
+			if validationErr := validate.Struct(update); validationErr != nil || update.Category_id == nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "every entry needs a category_id and a position"})
+				return
+			}
+			set := bson.M{"position": *update.Position, "updated_at": now}
+			if update.Parent_id != nil {
+				var category models.Category
+				if err := categoryCollection.FindOne(ctx, bson.M{"category_id": update.Category_id}).Decode(&category); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Category " + *update.Category_id + " was not found"})
+					return
+				}
+				if *update.Parent_id == "" {
+					set["parent_id"] = nil
+				} else if err := checkCategoryParent(ctx, category, *update.Parent_id); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				} else {
+					set["parent_id"] = *update.Parent_id
+				}
+			}
+			writes = append(writes, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"category_id": update.Category_id}).
+				SetUpdate(bson.M{"$set": set}))
+		}
+		if len(writes) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "nothing to reorder"})
+			return
+		}
+
+		result, err := categoryCollection.BulkWrite(ctx, writes)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Categories were not reordered"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// ReorderFoods sets the position (and optionally the category) of many foods at once.
+func ReorderFoods() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var updates []PositionUpdate
+
+		if err := c.BindJSON(&updates); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		writes := []mongo.WriteModel{}
+		for _, update := range updates {
+			if validationErr := validate.Struct(update); validationErr != nil || update.Food_id == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "every entry needs a food_id and a position"})
+				return
+			}
+			set := bson.M{"position": *update.Position, "updated_at": now}
+			if update.Category_id != nil {
+				var food models.Food
+				if err := foodCollection.FindOne(ctx, bson.M{"food_id": update.Food_id}).Decode(&food); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Food " + update.Food_id + " was not found"})
+					return
+				}
+				if *update.Category_id == "" {
+					set["category_id"] = nil
+				} else if err := checkCategoryInMenu(ctx, *update.Category_id, *food.Menu_id); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				} else {
+					set["category_id"] = *update.Category_id
+				}
+			}
+			writes = append(writes, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"food_id": update.Food_id}).
+				SetUpdate(bson.M{"$set": set}))
+		}
+		if len(writes) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "nothing to reorder"})
+			return
+		}
+
+		result, err := foodCollection.BulkWrite(ctx, writes)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Food items were not reordered"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// GetMenuTree returns the menu with its nested categories and foods, sorted for display.
+// Foods taken off the menu are only listed with ?include_off_menu=true.
+func GetMenuTree() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		menuId := c.Param("menu_id")
+
+		foodFilter := bson.M{"off_menu": bson.M{"$ne": true}}
+		if c.Query("include_off_menu") == "true" {
+			foodFilter = bson.M{}
+		}
+		tree, err := buildMenuTree(ctx, menuId, foodFilter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while building the menu tree"})
+			return
+		}
+		localizeTree(&tree, requestLocales(c))
+
+		c.JSON(http.StatusOK, tree)
+	}
+}
+
+// builds the tree of a menu from the foods matching foodFilter.
+func buildMenuTree(ctx context.Context, menuId string, foodFilter bson.M) (MenuTree, error) {
+	var tree MenuTree
+	if err := menuCollection.FindOne(ctx, bson.M{"menu_id": menuId}).Decode(&tree.Menu); err != nil {
+		return tree, err
+	}
+
+	var categories []models.Category
+	cursor, err := categoryCollection.Find(ctx, bson.M{"menu_id": menuId}, displayOrder)
+	if err != nil {
+		return tree, err
+	}
+	if err = cursor.All(ctx, &categories); err != nil {
+		return tree, err
+	}
+
+	filter := bson.M{"menu_id": menuId}
+	for k, v := range foodFilter {
+		filter[k] = v
+	}
+	var foods []models.Food
+	cursor, err = foodCollection.Find(ctx, filter, displayOrder)
+	if err != nil {
+		return tree, err
+	}
+	if err = cursor.All(ctx, &foods); err != nil {
+		return tree, err
+	}
+
+	nodes := map[string]*CategoryNode{}
+	for _, category := range categories {
+		node := &CategoryNode{Category_id: category.Category_id, Name: *category.Name, Translations: category.Translations, Foods: []models.Food{}, Children: []*CategoryNode{}}
+		if category.Position != nil {
+			node.Position = *category.Position
+		}
+		nodes[category.Category_id] = node
+	}
+
+	tree.Categories = []*CategoryNode{}
+	for _, category := range categories {
+		node := nodes[category.Category_id]
+		if parent, ok := nodes[stringValue(category.Parent_id)]; ok {
+			parent.Children = append(parent.Children, node)
+		} else {
+			tree.Categories = append(tree.Categories, node)
+		}
+	}
+
+	tree.Uncategorized = []models.Food{}
+	for _, food := range foods {
+		if node, ok := nodes[stringValue(food.Category_id)]; ok {
+			node.Foods = append(node.Foods, food)
+		} else {
+			tree.Uncategorized = append(tree.Uncategorized, food)
+		}
+	}
+	return tree, nil
+}
+
+// checks the category exists and belongs to the given menu.
+func checkCategoryInMenu(ctx context.Context, categoryId string, menuId string) error {
+	count, err := categoryCollection.CountDocuments(ctx, bson.M{"category_id": categoryId, "menu_id": menuId})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.New("category " + categoryId + " was not found on this menu")
+	}
+	return nil
+}
+
+// checks parentId can become the parent of category: same menu, and not the
+// category itself or one of its descendants.
+func checkCategoryParent(ctx context.Context, category models.Category, parentId string) error {
+	if err := checkCategoryInMenu(ctx, parentId, *category.Menu_id); err != nil {
+		return err
+	}
+	for id := parentId; id != ""; {
+		if id == category.Category_id {
+			return errors.New("a category cannot be moved under itself")
+		}
+		var ancestor models.Category
+		if err := categoryCollection.FindOne(ctx, bson.M{"category_id": id}).Decode(&ancestor); err != nil {
+			return err
+		}
+		id = stringValue(ancestor.Parent_id)
+	}
+	return nil
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
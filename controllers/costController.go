@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"context"
+	"golang-Restaurant-Management-backend/models"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// FoodCostLine is the cost of one ingredient in a dish.
+type FoodCostLine struct {
+	Ingredient_id string   `json:"ingredient_id"`
+	Name          string   `json:"name"`
+	Quantity      float64  `json:"quantity"`
+	Unit          string   `json:"unit"`
+	Unit_cost     *float64 `json:"unit_cost"`
+	Cost          float64  `json:"cost"`
+}
+
+// FoodCost is the theoretical cost of one portion, computed from the recipe and
+// the latest ingredient costs. Complete is false when an ingredient has no cost yet.
+type FoodCost struct {
+	Food_id        string         `json:"food_id"`
+	Price          float64        `json:"price"`
+	Cost           float64        `json:"cost"`
+	Margin         float64        `json:"margin"`
+	Margin_percent float64        `json:"margin_percent"`
+	Complete       bool           `json:"complete"`
+	Lines          []FoodCostLine `json:"lines"`
+}
+
+// GetFoodCost returns the theoretical cost and margin of a food, optionally with modifiers
+// (?modifier=extra%20cheese&modifier=...).
+func GetFoodCost() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		foodId := c.Param("food_id")
+		var food models.Food
+
+		err := foodCollection.FindOne(ctx, bson.M{"food_id": foodId}).Decode(&food)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while fetching the food item"})
+			return
+		}
+
+		cost, err := foodCost(ctx, foodId, c.QueryArray("modifier"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while costing the food item"})
+			return
+		}
+
+		price := 0.0
+		if food.Price != nil {
+			price = *food.Price
+		}
+		cost.setPrice(price)
+
+		c.JSON(http.StatusOK, cost)
+	}
+}
+
+// sets the selling price and the margin it leaves over the cost.
+func (cost *FoodCost) setPrice(price float64) {
+	cost.Price = price
+	cost.Margin = toFixed(cost.Price-cost.Cost, 2)
+	cost.Margin_percent = 0
+	if cost.Price > 0 {
+		cost.Margin_percent = toFixed(cost.Margin/cost.Price*100, 2)
+	}
+}
+
+// costs one portion of a food from its recipe and the ingredients' unit costs.
+func foodCost(ctx context.Context, foodId string, modifiers []string) (FoodCost, error) {
+	cost := FoodCost{Food_id: foodId, Complete: true, Lines: []FoodCostLine{}}
+
+	usage, err := recipeUsage(ctx, foodId, modifiers)
+	if err != nil {
+		return cost, err
+	}
+	if len(usage) == 0 {
+		cost.Complete = false
+	}
+
+	for ingredientId, quantity := range usage {
+		var ingredient models.Ingredient
+		line := FoodCostLine{Ingredient_id: ingredientId, Quantity: quantity}
+		if err := ingredientCollection.FindOne(ctx, bson.M{"ingredient_id": ingredientId}).Decode(&ingredient); err != nil {
+			return cost, err
+		}
+		line.Name = *ingredient.Name
+		line.Unit = *ingredient.Unit
+		line.Unit_cost = ingredient.Unit_cost
+		if ingredient.Unit_cost == nil {
+			cost.Complete = false
+		} else {
+			line.Cost = toFixed(quantity*(*ingredient.Unit_cost), 4)
+		}
+		cost.Cost += line.Cost
+		cost.Lines = append(cost.Lines, line)
+	}
+	cost.Cost = toFixed(cost.Cost, 2)
+	return cost, nil
+}
+
+// returns the cost to store on an order item at sale time. Costing problems are
+// logged and leave the cost empty rather than failing the order.
+func snapshotCost(ctx context.Context, foodId string, modifiers []string) *float64 {
+	cost, err := foodCost(ctx, foodId, modifiers)
+	if err != nil {
+		log.Println("costing failed for food", foodId, err)
+		return nil
+	}
+	return &cost.Cost
+}
+
+// stores the cost of each component, and their sum on the bundle line.
+func snapshotBundleCost(ctx context.Context, bundleItems []models.OrderItem) {
+	total := 0.0
+	for i := 1; i < len(bundleItems); i++ {
+		bundleItems[i].Unit_cost = snapshotCost(ctx, *bundleItems[i].Food_id, bundleItems[i].Modifiers)
+		if bundleItems[i].Unit_cost != nil {
+			total += *bundleItems[i].Unit_cost
+		}
+	}
+	total = toFixed(total, 2)
+	bundleItems[0].Unit_cost = &total
+}
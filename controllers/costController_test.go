@@ -0,0 +1,25 @@
+package controllers
+
+import "testing"
+
+func TestFoodCostSetPrice(t *testing.T) {
+	tests := []struct {
+		cost, price   float64
+		margin        float64
+		marginPercent float64
+	}{
+		{3, 12, 9, 75},
+		{3.333, 10, 6.67, 66.7},
+		{0, 8, 8, 100},
+		{5, 4, -1, -25},
+		{2.5, 0, -2.5, 0},
+	}
+	for _, test := range tests {
+		cost := FoodCost{Cost: test.cost, Margin_percent: 50}
+		cost.setPrice(test.price)
+		if cost.Price != test.price || cost.Margin != test.margin || cost.Margin_percent != test.marginPercent {
+			t.Errorf("cost %v at price %v: margin %v (%v%%), want %v (%v%%)",
+				test.cost, test.price, cost.Margin, cost.Margin_percent, test.margin, test.marginPercent)
+		}
+	}
+}
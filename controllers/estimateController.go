@@ -0,0 +1,426 @@
+package controllers
+
+import (
+	"context"
+	"golang-Restaurant-Management-backend/models"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// how many preparations of a food within prepHistoryDays it takes before their
+// average replaces the prep time set on the food.
+const prepSampleMinimum = 5
+const prepHistoryDays = 30
+
+// estimates closer than this to the stored ones are not written or pushed to screens.
+const estimateTolerance = time.Minute
+
+// ReadyEstimate is when an order and each of its items are expected to be ready.
+type ReadyEstimate struct {
+	Order_id           string         `json:"order_id"`
+	Status             string         `json:"status"`
+	Estimated_ready_at *time.Time     `json:"estimated_ready_at"`
+	Items              []ItemEstimate `json:"items"`
+}
+
+type ItemEstimate struct {
+	Order_item_id      string     `json:"order_item_id"`
+	Status             string     `json:"status"`
+	Station_id         *string    `json:"station_id"`
+	Prep_seconds       int        `json:"prep_seconds"`
+	Estimated_ready_at *time.Time `json:"estimated_ready_at"`
+}
+
+// GetOrderEstimate returns when the order is expected to be ready, given what
+// the kitchen is working on now.
+func GetOrderEstimate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var order models.Order
+		if err := orderCollection.FindOne(ctx, bson.M{"order_id": c.Param("order_id")}).Decode(&order); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order was not found"})
+			return
+		}
+
+		estimate, err := estimateOrder(ctx, order)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while estimating the order"})
+			return
+		}
+
+		c.JSON(http.StatusOK, estimate)
+	}
+}
+
+// prepTimes is how long the kitchen takes for each food: the average of its
+// recent preparations when there are enough of them, otherwise the prep time
+// set on the food, otherwise defaultPrepMinutes.
+type prepTimes map[string]time.Duration
+
+func loadPrepTimes(ctx context.Context) (prepTimes, error) {
+	prep := prepTimes{}
+	pipeline := mongo.Pipeline{
+		{{"$match", bson.D{
+			{"food_id", bson.D{{"$ne", nil}}},
+			{"prep_seconds", bson.D{{"$gt", 0}}},
+			{"ready_at", bson.D{{"$gte", time.Now().AddDate(0, 0, -prepHistoryDays)}}},
+		}}},
+		{{"$group", bson.D{
+			{"_id", "$food_id"},
+			{"average", bson.D{{"$avg", "$prep_seconds"}}},
+			{"count", bson.D{{"$sum", 1}}},
+		}}},
+	}
+	var observed []struct {
+		Food_id string  `bson:"_id"`
+		Average float64 `bson:"average"`
+		Count   int     `bson:"count"`
+	}
+	if err := aggregateInto(ctx, pipeline, &observed); err != nil {
+		return nil, err
+	}
+	for _, food := range observed {
+		if food.Count >= prepSampleMinimum {
+			prep[food.Food_id] = time.Duration(food.Average) * time.Second
+		}
+	}
+
+	var foods []models.Food
+	cursor, err := foodCollection.Find(ctx, bson.M{"prep_minutes": bson.M{"$ne": nil}})
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(ctx, &foods); err != nil {
+		return nil, err
+	}
+	for _, food := range foods {
+		if _, ok := prep[food.Food_id]; !ok && food.Prep_minutes != nil {
+			prep[food.Food_id] = time.Duration(*food.Prep_minutes) * time.Minute
+		}
+	}
+	return prep, nil
+}
+
+// of returns the prep time of an order item. Bundle lines take no time
+// themselves, their components are prepared.
+func (p prepTimes) of(orderItem models.OrderItem) time.Duration {
+	if orderItem.Food_id == nil {
+		return 0
+	}
+	if prep, ok := p[*orderItem.Food_id]; ok {
+		return prep
+	}
+	return defaultPrepMinutes * time.Minute
+}
+
+// kitchenLoad is the kitchen working through its fired items, oldest first,
+// each station on as many at once as its concurrency. Lanes hold when each
+// of a station's places becomes free.
+type kitchenLoad struct {
+	now     time.Time
+	prep    prepTimes
+	lanes   map[string][]time.Time
+	readyAt map[string]time.Time
+}
+
+func loadKitchen(ctx context.Context) (*kitchenLoad, error) {
+	prep, err := loadPrepTimes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	load := &kitchenLoad{now: time.Now(), prep: prep, lanes: map[string][]time.Time{}, readyAt: map[string]time.Time{}}
+
+	var stations []models.Station
+	cursor, err := stationCollection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(ctx, &stations); err != nil {
+		return nil, err
+	}
+	for _, station := range stations {
+		concurrency := 1
+		if station.Concurrency != nil && *station.Concurrency > 0 {
+			concurrency = *station.Concurrency
+		}
+		load.lanes[station.Station_id] = make([]time.Time, concurrency)
+	}
+
+	var fired []models.OrderItem
+	filter := bson.M{"status": models.OrderItemStatusFired, "item_type": bson.M{"$ne": models.OrderItemTypeBundle}}
+	if cursor, err = orderItemCollection.Find(ctx, filter); err != nil {
+		return nil, err
+	}
+	if err = cursor.All(ctx, &fired); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(fired, func(i, j int) bool { return startedAt(fired[i]).Before(startedAt(fired[j])) })
+	for _, orderItem := range fired {
+		load.readyAt[orderItem.Order_item_id] = load.place(orderItem, startedAt(orderItem))
+	}
+	return load, nil
+}
+
+// place puts an item fired at firedAt on the first free place of its station
+// and returns when it should be ready. Items still cooking past their estimate
+// are expected any moment now. Items of foods without a station do not wait.
+func (k *kitchenLoad) place(orderItem models.OrderItem, firedAt time.Time) time.Time {
+	prep := k.prep.of(orderItem)
+	lanes, ok := k.lanes[stringValue(orderItem.Station_id)]
+	if !ok {
+		return latest(firedAt.Add(prep), k.now)
+	}
+	first := 0
+	for i := range lanes {
+		if lanes[i].Before(lanes[first]) {
+			first = i
+		}
+	}
+	readyAt := latest(latest(lanes[first], firedAt).Add(prep), k.now)
+	lanes[first] = readyAt
+	return readyAt
+}
+
+// fork returns a copy of the load that further items can be placed on
+// without changing it.
+func (k *kitchenLoad) fork() *kitchenLoad {
+	lanes := map[string][]time.Time{}
+	for station, free := range k.lanes {
+		lanes[station] = append([]time.Time{}, free...)
+	}
+	return &kitchenLoad{now: k.now, prep: k.prep, lanes: lanes, readyAt: k.readyAt}
+}
+
+// estimateOrder works out when the order will be ready given the current load
+// of the kitchen.
+func estimateOrder(ctx context.Context, order models.Order) (ReadyEstimate, error) {
+	load, err := loadKitchen(ctx)
+	if err != nil {
+		return ReadyEstimate{}, err
+	}
+	orderItems, err := estimatedItems(ctx, bson.M{"order_id": order.Order_id})
+	if err != nil {
+		return ReadyEstimate{}, err
+	}
+	return load.estimate(order, orderItems), nil
+}
+
+// estimate works out when each item of the order will be ready: ready items
+// when they got ready, fired items where the kitchen load puts them. Held
+// items only count while nothing of the order has been fired, as if it were
+// sent now; a scheduled order is expected when it is wanted. Bundle lines are
+// ready with their last component. The order is ready with its last item.
+func (k *kitchenLoad) estimate(order models.Order, orderItems []models.OrderItem) ReadyEstimate {
+	estimate := ReadyEstimate{Order_id: order.Order_id, Status: orderStatus(order), Items: []ItemEstimate{}}
+
+	sent := false
+	for _, orderItem := range orderItems {
+		if itemStatus(orderItem) != models.OrderItemStatusHeld {
+			sent = true
+		}
+	}
+	preview := k.fork()
+
+	readyAt := map[string]time.Time{}
+	for _, orderItem := range orderItems {
+		if orderItem.Item_type == models.OrderItemTypeBundle {
+			continue
+		}
+		switch itemStatus(orderItem) {
+		case models.OrderItemStatusReady, models.OrderItemStatusServed:
+			if orderItem.Ready_at != nil {
+				readyAt[orderItem.Order_item_id] = *orderItem.Ready_at
+			}
+		case models.OrderItemStatusFired:
+			if at, ok := k.readyAt[orderItem.Order_item_id]; ok {
+				readyAt[orderItem.Order_item_id] = at
+			}
+		case models.OrderItemStatusHeld:
+			if order.Status == models.OrderStatusScheduled && order.Scheduled_for != nil {
+				readyAt[orderItem.Order_item_id] = *order.Scheduled_for
+			} else if !sent {
+				readyAt[orderItem.Order_item_id] = preview.place(orderItem, k.now)
+			}
+		}
+	}
+	for _, orderItem := range orderItems {
+		if parent := orderItem.Parent_order_item_id; parent != nil {
+			if at, ok := readyAt[orderItem.Order_item_id]; ok {
+				readyAt[*parent] = latest(readyAt[*parent], at)
+			}
+		}
+	}
+
+	for _, orderItem := range orderItems {
+		item := ItemEstimate{
+			Order_item_id: orderItem.Order_item_id,
+			Status:        itemStatus(orderItem),
+			Station_id:    orderItem.Station_id,
+			Prep_seconds:  int(k.prep.of(orderItem).Seconds()),
+		}
+		if at, ok := readyAt[orderItem.Order_item_id]; ok {
+			at = at.Truncate(time.Second)
+			item.Estimated_ready_at = &at
+			if estimate.Estimated_ready_at == nil || at.After(*estimate.Estimated_ready_at) {
+				estimate.Estimated_ready_at = &at
+			}
+		}
+		estimate.Items = append(estimate.Items, item)
+	}
+	return estimate
+}
+
+// the items of the orders matching filter the kitchen makes or made, oldest first.
+func estimatedItems(ctx context.Context, filter bson.M) ([]models.OrderItem, error) {
+	filter["status"] = bson.M{"$nin": bson.A{models.OrderItemStatusVoided, models.OrderItemStatusWasted}}
+	var orderItems []models.OrderItem
+	cursor, err := orderItemCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{"created_at", 1}}))
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(ctx, &orderItems); err != nil {
+		return nil, err
+	}
+	return orderItems, nil
+}
+
+// refreshReadyEstimates stores new ready estimates on the fired items and
+// their orders, and on orderId, after the kitchen load changed. It returns the
+// other orders whose estimates moved, so their tickets can be pushed again.
+func refreshReadyEstimates(ctx context.Context, orderId string) ([]string, error) {
+	load, err := loadKitchen(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	orderIds := bson.A{orderId}
+	firedOrders, err := orderItemCollection.Distinct(ctx, "order_id", bson.M{"status": models.OrderItemStatusFired})
+	if err != nil {
+		return nil, err
+	}
+	orderIds = append(orderIds, firedOrders...)
+
+	var orders []models.Order
+	cursor, err := orderCollection.Find(ctx, bson.M{"order_id": bson.M{"$in": orderIds}})
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(ctx, &orders); err != nil {
+		return nil, err
+	}
+	orderItems, err := estimatedItems(ctx, bson.M{"order_id": bson.M{"$in": orderIds}})
+	if err != nil {
+		return nil, err
+	}
+	itemsByOrder := map[string][]models.OrderItem{}
+	for _, orderItem := range orderItems {
+		itemsByOrder[orderItem.Order_id] = append(itemsByOrder[orderItem.Order_id], orderItem)
+	}
+
+	moved := []string{}
+	orderWrites := []mongo.WriteModel{}
+	itemWrites := []mongo.WriteModel{}
+	for _, order := range orders {
+		estimate := load.estimate(order, itemsByOrder[order.Order_id])
+		changed := false
+		for i, orderItem := range itemsByOrder[order.Order_id] {
+			at := estimate.Items[i].Estimated_ready_at
+			if estimateMoved(orderItem.Estimated_ready_at, at) {
+				changed = true
+				itemWrites = append(itemWrites, mongo.NewUpdateOneModel().
+					SetFilter(bson.M{"order_item_id": orderItem.Order_item_id}).
+					SetUpdate(bson.M{"$set": bson.M{"estimated_ready_at": at}}))
+			}
+		}
+		if estimateMoved(order.Estimated_ready_at, estimate.Estimated_ready_at) {
+			changed = true
+			orderWrites = append(orderWrites, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"order_id": order.Order_id}).
+				SetUpdate(bson.M{"$set": bson.M{"estimated_ready_at": estimate.Estimated_ready_at}}))
+		}
+		if changed && order.Order_id != orderId {
+			moved = append(moved, order.Order_id)
+		}
+	}
+
+	if len(itemWrites) > 0 {
+		if _, err := orderItemCollection.BulkWrite(ctx, itemWrites, options.BulkWrite().SetOrdered(false)); err != nil {
+			return nil, err
+		}
+	}
+	if len(orderWrites) > 0 {
+		if _, err := orderCollection.BulkWrite(ctx, orderWrites, options.BulkWrite().SetOrdered(false)); err != nil {
+			return nil, err
+		}
+	}
+	return moved, nil
+}
+
+// stores the estimate of a new or changed order on it and returns it. Failing
+// to estimate is logged, it does not fail the change.
+func storeOrderEstimate(ctx context.Context, orderId string) *time.Time {
+	var order models.Order
+	if err := orderCollection.FindOne(ctx, bson.M{"order_id": orderId}).Decode(&order); err != nil {
+		log.Println("estimating order", orderId, "failed:", err)
+		return nil
+	}
+	estimate, err := estimateOrder(ctx, order)
+	if err != nil {
+		log.Println("estimating order", orderId, "failed:", err)
+		return nil
+	}
+	update := bson.M{"$set": bson.M{"estimated_ready_at": estimate.Estimated_ready_at}}
+	if _, err := orderCollection.UpdateOne(ctx, bson.M{"order_id": orderId}, update); err != nil {
+		log.Println("storing the estimate of order", orderId, "failed:", err)
+	}
+	return estimate.Estimated_ready_at
+}
+
+// records how long the kitchen took for the ready items matching filter, from
+// when they were last fired until they got ready.
+func recordPrepTimes(ctx context.Context, filter bson.M) error {
+	filter["status"] = models.OrderItemStatusReady
+	filter["fired_at"] = bson.M{"$ne": nil}
+	filter["ready_at"] = bson.M{"$ne": nil}
+	update := mongo.Pipeline{
+		{{"$set", bson.D{
+			{"prep_seconds", bson.D{{"$toInt", bson.D{{"$divide", bson.A{
+				bson.D{{"$subtract", bson.A{"$ready_at", "$fired_at"}}}, 1000,
+			}}}}}},
+		}}},
+	}
+	_, err := orderItemCollection.UpdateMany(ctx, filter, update)
+	return err
+}
+
+func estimateMoved(stored *time.Time, estimated *time.Time) bool {
+	if stored == nil || estimated == nil {
+		return (stored == nil) != (estimated == nil)
+	}
+	diff := stored.Sub(*estimated)
+	return diff >= estimateTolerance || diff <= -estimateTolerance
+}
+
+// when an item started cooking: when it was last fired, or created if unknown.
+func startedAt(orderItem models.OrderItem) time.Time {
+	if orderItem.Fired_at != nil {
+		return *orderItem.Fired_at
+	}
+	return orderItem.Created_at
+}
+
+func latest(a time.Time, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
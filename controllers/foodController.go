@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"golang-Restaurant-Management-backend/database"
 	"golang-Restaurant-Management-backend/models"
+	"golang-Restaurant-Management-backend/search"
 	"log"
 	"math"
 	"net/http"
@@ -21,6 +22,7 @@ import (
 
 var foodCollection *mongo.Collection = database.OpenCollection(database.Client, "food")
 var validate = validator.New()
+var foodIndex search.Index = search.NewMongoIndex(foodCollection, menuCollection)
 
 func GetFoods() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -65,10 +67,85 @@ func GetFoods() gin.HandlerFunc {
 			log.Fatal(err)
 		}
 
+		// Translate the page of food items to the requested language.
+		if len(allFoods) > 0 {
+			locales := requestLocales(c)
+			if foodItems, ok := allFoods[0]["food_items"].(bson.A); ok {
+				for _, foodItem := range foodItems {
+					if doc, ok := foodItem.(bson.M); ok {
+						localizeDoc(doc, locales)
+					}
+				}
+			}
+		}
+
 		c.JSON(http.StatusOK, allFoods[0])
 	}
 }
 
+// SearchFoods ranks foods against the q parameter, using typo-tolerant matching over
+// names, tags and descriptions, with optional menu, category and price filters.
+func SearchFoods() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		recordPerPage, err := strconv.Atoi(c.Query("recordPerPage"))
+		if err != nil || recordPerPage < 1 {
+			recordPerPage = 10
+		}
+		page, err := strconv.Atoi(c.Query("page"))
+		if err != nil || page < 1 {
+			page = 1
+		}
+
+		query := search.FoodQuery{
+			Text:     c.Query("q"),
+			Menu_id:  c.Query("menu_id"),
+			Category: c.Query("category"),
+			Sort:     c.Query("sort"),
+			Skip:     (page - 1) * recordPerPage,
+			Limit:    recordPerPage,
+			// Foods taken off their menu cannot be ordered, menu editors can still ask for them.
+			Include_off_menu: c.Query("include_off_menu") == "true",
+		}
+		if !search.ValidSort(query.Sort) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be relevance, price_asc or price_desc"})
+			return
+		}
+
+		// Parse the optional price range.
+		if value := c.Query("min_price"); value != "" {
+			minPrice, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "min_price must be a number"})
+				return
+			}
+			query.Min_price = &minPrice
+		}
+		if value := c.Query("max_price"); value != "" {
+			maxPrice, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "max_price must be a number"})
+				return
+			}
+			query.Max_price = &maxPrice
+		}
+
+		result, err := foodIndex.SearchFoods(ctx, query)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while searching food items"})
+			return
+		}
+		locales := requestLocales(c)
+		for i := range result.Food_items {
+			localizeFood(&result.Food_items[i].Food, locales)
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
 func GetFood() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
@@ -82,6 +159,7 @@ func GetFood() gin.HandlerFunc {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while fetching the food item"})
 		}
 
+		localizeFood(&food, requestLocales(c))
 		c.JSON(http.StatusOK, food)
 	}
 }
@@ -114,6 +192,17 @@ func CreateFood() gin.HandlerFunc {
 			return
 		}
 
+		// A category, when given, must belong to the same menu.
+		if food.Category_id != nil {
+			if err := checkCategoryInMenu(ctx, *food.Category_id, *food.Menu_id); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		// On a versioned menu a new food is only served once a version with it is published.
+		food.Off_menu = menu.Published_version_id != nil
+
 		// Set creation and update timestamps, and generate a unique ID for the food item.
 		food.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		food.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
@@ -132,6 +221,12 @@ func CreateFood() gin.HandlerFunc {
 			return
 		}
 
+		// The first price of a food starts its price history.
+		err = recordPriceChange(ctx, c, models.PriceChange{Food_id: food.Food_id, New_price: *food.Price, Source: models.PriceSourceCreate})
+		if err != nil {
+			log.Println("recording the price of food", food.Food_id, "failed:", err)
+		}
+
 		defer cancel()
 
 		c.JSON(http.StatusOK, result)
@@ -164,15 +259,89 @@ func UpdateFood() gin.HandlerFunc {
 
 		var updateObj primitive.D
 
+		// The content of a food on a versioned menu changes through drafts, so
+		// that what is served stays what was published.
+		if food.Name != nil || food.Price != nil || food.Description != nil || food.Tags != nil || food.Food_image != nil {
+			var current models.Food
+			if err := foodCollection.FindOne(ctx, bson.M{"food_id": foodId}).Decode(&current); err == nil && current.Menu_id != nil {
+				if err := checkMenuUnversioned(ctx, *current.Menu_id); err != nil {
+					respondStatusError(c, err)
+					return
+				}
+			}
+		}
+
 		// Append updated fields to the update object.
 		if food.Name != nil {
 			updateObj = append(updateObj, bson.E{"name", food.Name})
 		}
+		// Keep the old price for the price history.
+		var oldPrice *float64
 		if food.Price != nil {
+			var current models.Food
+			if err := foodCollection.FindOne(ctx, bson.M{"food_id": foodId}).Decode(&current); err == nil {
+				oldPrice = current.Price
+			}
+			var num = toFixed(*food.Price, 2)
+			food.Price = &num
 			updateObj = append(updateObj, bson.E{"price", food.Price})
 		}
 		if food.Food_image != nil {
 			updateObj = append(updateObj, bson.E{"food_image", food.Food_image})
+			// The resized images belong to the uploaded image, not to one set by URL.
+			var current models.Food
+			if err := foodCollection.FindOne(ctx, bson.M{"food_id": foodId}).Decode(&current); err == nil && stringValue(current.Food_image) != *food.Food_image {
+				updateObj = append(updateObj, bson.E{"images", nil})
+			}
+		}
+		if food.Description != nil {
+			updateObj = append(updateObj, bson.E{"description", food.Description})
+		}
+		if food.Tags != nil {
+			updateObj = append(updateObj, bson.E{"tags", food.Tags})
+		}
+		if food.Sku != nil {
+			updateObj = append(updateObj, bson.E{"sku", food.Sku})
+		}
+		if food.Allergens != nil {
+			updateObj = append(updateObj, bson.E{"allergens", food.Allergens})
+		}
+		if food.Prep_minutes != nil {
+			if validationErr := validate.StructPartial(food, "Prep_minutes"); validationErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+				return
+			}
+			updateObj = append(updateObj, bson.E{"prep_minutes", food.Prep_minutes})
+		}
+		if food.Nutrition != nil {
+			if validationErr := validate.Struct(food.Nutrition); validationErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+				return
+			}
+			updateObj = append(updateObj, bson.E{"nutrition", food.Nutrition})
+		}
+
+		if food.Position != nil {
+			updateObj = append(updateObj, bson.E{"position", food.Position})
+		}
+		// A new category must belong to the food's (new or current) menu.
+		if food.Category_id != nil {
+			menuId := food.Menu_id
+			if menuId == nil {
+				var current models.Food
+				if err := foodCollection.FindOne(ctx, bson.M{"food_id": foodId}).Decode(&current); err == nil {
+					menuId = current.Menu_id
+				}
+			}
+			if menuId == nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Food item was not found"})
+				return
+			}
+			if err := checkCategoryInMenu(ctx, *food.Category_id, *menuId); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			updateObj = append(updateObj, bson.E{"category_id", food.Category_id})
 		}
 
 		// Before update the menu, check if the food's Menu_id is provided.
@@ -215,6 +384,13 @@ func UpdateFood() gin.HandlerFunc {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
 			return
 		}
+
+		if food.Price != nil {
+			err = recordPriceChange(ctx, c, models.PriceChange{Food_id: foodId, Old_price: oldPrice, New_price: *food.Price, Source: models.PriceSourceUpdate})
+			if err != nil {
+				log.Println("recording the price of food", foodId, "failed:", err)
+			}
+		}
 		defer cancel()
 
 		c.JSON(http.StatusOK, result)
@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"golang-Restaurant-Management-backend/helpers"
+	"golang-Restaurant-Management-backend/models"
+	"golang-Restaurant-Management-backend/storage"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var imageStore storage.Store = openImageStore()
+
+func openImageStore() storage.Store {
+	store, err := storage.NewFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return store
+}
+
+// UploadFoodImage stores a multipart "image" file for a food item together with its
+// resized JPEG and WebP variants, and points food_image at the medium rendition.
+func UploadFoodImage() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		foodId := c.Param("food_id")
+
+		var food models.Food
+		if err := foodCollection.FindOne(ctx, bson.M{"food_id": foodId}).Decode(&food); err != nil {
+			if err == mongo.ErrNoDocuments {
+				c.JSON(http.StatusNotFound, gin.H{"error": "food item was not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the food item"})
+			return
+		}
+		// Like any other content, the image of a food on a versioned menu changes through drafts.
+		if food.Menu_id != nil {
+			if err := checkMenuUnversioned(ctx, *food.Menu_id); err != nil {
+				respondStatusError(c, err)
+				return
+			}
+		}
+
+		// Leave room for the multipart framing around the file itself.
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, helpers.MAX_IMAGE_BYTES+64<<10)
+		header, err := c.FormFile("image")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "an image file is required in the \"image\" field"})
+			return
+		}
+		if header.Size > helpers.MAX_IMAGE_BYTES {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": helpers.ErrImageTooLarge.Error()})
+			return
+		}
+		file, err := header.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		data, err := io.ReadAll(io.LimitReader(file, helpers.MAX_IMAGE_BYTES+1))
+		file.Close()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		variants, err := helpers.ProcessImage(data)
+		if errors.Is(err, helpers.ErrImageTooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Keys carry a hash of the upload so every URL is immutable and can be cached forever.
+		sum := sha256.Sum256(data)
+		prefix := "foods/" + foodId + "/" + hex.EncodeToString(sum[:8]) + "/"
+		images := map[string]string{}
+		for _, variant := range variants {
+			if err := imageStore.Put(ctx, prefix+variant.File, variant.Data, variant.Content_type); err != nil {
+				log.Println(err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "image could not be stored"})
+				return
+			}
+			images[variant.Name] = "/images/" + prefix + variant.File
+		}
+
+		foodImage := images["medium"]
+		update := bson.M{"$set": bson.M{"food_image": foodImage, "images": images, "updated_at": time.Now()}}
+		if _, err := foodCollection.UpdateOne(ctx, bson.M{"food_id": foodId}, update); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "food item update failed"})
+			return
+		}
+
+		food.Food_image = &foodImage
+		food.Images = images
+		c.JSON(http.StatusOK, food)
+	}
+}
+
+// ServeImage returns a stored image. Image keys never change content, so responses
+// are cacheable for a year and conditional requests for images that still exist
+// are answered with 304.
+func ServeImage() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		key := strings.TrimPrefix(c.Param("filepath"), "/")
+
+		sum := sha256.Sum256([]byte(key))
+		etag := `"` + hex.EncodeToString(sum[:12]) + `"`
+		if _, err := imageStore.Stat(ctx, key); errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "image was not found"})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if c.GetHeader("If-None-Match") == etag {
+			c.Header("ETag", etag)
+			c.Status(http.StatusNotModified)
+			return
+		}
+
+		body, object, err := imageStore.Open(ctx, key)
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "image was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer body.Close()
+
+		headers := map[string]string{
+			"Cache-Control": "public, max-age=31536000, immutable",
+			"ETag":          etag,
+		}
+		if !object.Modified.IsZero() {
+			headers["Last-Modified"] = object.Modified.UTC().Format(http.TimeFormat)
+		}
+		c.DataFromReader(http.StatusOK, object.Size, object.Content_type, body, headers)
+	}
+}
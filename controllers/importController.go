@@ -0,0 +1,458 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"golang-Restaurant-Management-backend/menuio"
+	"golang-Restaurant-Management-backend/models"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ImportResult summarizes an import. On a dry run, or when any row has an error,
+// nothing is written and the counts say what the import would have done.
+type ImportResult struct {
+	Dry_run            bool              `json:"dry_run"`
+	Applied            bool              `json:"applied"`
+	Rows               int               `json:"rows"`
+	Menus_created      int               `json:"menus_created"`
+	Categories_created int               `json:"categories_created"`
+	Foods_created      int               `json:"foods_created"`
+	Foods_updated      int               `json:"foods_updated"`
+	Errors             []menuio.RowError `json:"errors"`
+}
+
+// menuImport resolves rows against the database. With apply unset it only looks
+// things up, handing out placeholder IDs for the records it would create.
+type menuImport struct {
+	c          *gin.Context
+	apply      bool
+	result     ImportResult
+	menus      map[string]models.Menu
+	versioned  map[string]bool
+	categories map[string]string
+	positions  map[string]int
+	findFood   func(ctx context.Context, filter bson.M) (models.Food, error)
+}
+
+// ImportMenus imports menus, categories and foods from a CSV or JSON body.
+// The format comes from ?format= or the Content-Type; ?dry_run=true only validates.
+func ImportMenus() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		format := c.Query("format")
+		if format == "" {
+			format = menuio.FormatJSON
+			if strings.Contains(c.ContentType(), "csv") {
+				format = menuio.FormatCSV
+			}
+		}
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		rows, parseErrs := menuio.Read(bytes.NewReader(body), format)
+		result, err := ImportMenuRows(ctx, c, rows, parseErrs, c.Query("dry_run") == "true")
+		if err != nil {
+			log.Println("menu import failed:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while importing the menu", "result": result})
+			return
+		}
+		if len(result.Errors) > 0 {
+			c.JSON(http.StatusBadRequest, result)
+			return
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// ExportMenus writes every menu, or only ?menu_id=, in the import format (?format=csv|json).
+func ExportMenus() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		format := c.DefaultQuery("format", menuio.FormatJSON)
+		if format != menuio.FormatCSV && format != menuio.FormatJSON {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or json"})
+			return
+		}
+		rows, err := ExportMenuRows(ctx, c.Query("menu_id"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while exporting the menus"})
+			return
+		}
+
+		contentType := "application/json"
+		if format == menuio.FormatCSV {
+			contentType = "text/csv"
+		}
+		c.Header("Content-Disposition", "attachment; filename=menus."+format)
+		c.Header("Content-Type", contentType+"; charset=utf-8")
+		c.Status(http.StatusOK)
+		if err := menuio.Write(c.Writer, format, rows); err != nil {
+			log.Println("menu export failed:", err)
+		}
+	}
+}
+
+// ImportMenuRows validates rows and, unless dryRun is set or a row has an error,
+// creates the missing menus and categories and upserts the foods by SKU.
+// c may be nil when called outside a request, e.g. from the menuimport command.
+func ImportMenuRows(ctx context.Context, c *gin.Context, rows []menuio.Row, parseErrs []menuio.RowError, dryRun bool) (ImportResult, error) {
+	check := &menuImport{c: c, findFood: findFood}
+	check.result.Errors = append(parseErrs, menuio.Validate(rows)...)
+	if len(check.result.Errors) == 0 {
+		if err := check.run(ctx, rows); err != nil {
+			return check.result, err
+		}
+	}
+	check.result.Rows = len(rows)
+	check.result.Dry_run = dryRun
+	if check.result.Errors == nil {
+		check.result.Errors = []menuio.RowError{}
+	}
+	if dryRun || len(check.result.Errors) > 0 {
+		return check.result, nil
+	}
+
+	apply := &menuImport{c: c, apply: true, findFood: findFood}
+	err := apply.run(ctx, rows)
+	apply.result.Rows = len(rows)
+	apply.result.Applied = err == nil
+	apply.result.Errors = []menuio.RowError{}
+	return apply.result, err
+}
+
+// ExportMenuRows lists the foods of a menu (every menu when menuId is empty)
+// in display order, as rows that ImportMenuRows accepts. Foods without a SKU
+// are exported with their food_id so they can still be upserted later.
+func ExportMenuRows(ctx context.Context, menuId string) ([]menuio.Row, error) {
+	filter := bson.M{}
+	if menuId != "" {
+		filter["menu_id"] = menuId
+	}
+	var menus []models.Menu
+	cursor, err := menuCollection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(ctx, &menus); err != nil {
+		return nil, err
+	}
+
+	rows := []menuio.Row{}
+	for _, menu := range menus {
+		tree, err := buildMenuTree(ctx, menu.Menu_id, bson.M{})
+		if err != nil {
+			return nil, err
+		}
+		var walk func(nodes []*CategoryNode, path []string)
+		walk = func(nodes []*CategoryNode, path []string) {
+			for _, node := range nodes {
+				nodePath := append(append([]string{}, path...), node.Name)
+				for _, food := range node.Foods {
+					rows = append(rows, exportRow(menu, menuio.JoinCategory(nodePath), food))
+				}
+				walk(node.Children, nodePath)
+			}
+		}
+		walk(tree.Categories, nil)
+		for _, food := range tree.Uncategorized {
+			rows = append(rows, exportRow(menu, "", food))
+		}
+	}
+	return rows, nil
+}
+
+func exportRow(menu models.Menu, category string, food models.Food) menuio.Row {
+	sku := food.Food_id
+	if food.Sku != nil && *food.Sku != "" {
+		sku = *food.Sku
+	}
+	return menuio.Row{
+		Menu:          menu.Name,
+		Menu_category: menu.Category,
+		Category:      category,
+		Sku:           sku,
+		Name:          stringValue(food.Name),
+		Description:   stringValue(food.Description),
+		Tags:          food.Tags,
+		Price:         food.Price,
+		Food_image:    stringValue(food.Food_image),
+		Position:      food.Position,
+	}
+}
+
+func (m *menuImport) run(ctx context.Context, rows []menuio.Row) error {
+	m.menus = map[string]models.Menu{}
+	m.versioned = map[string]bool{}
+	m.categories = map[string]string{}
+	m.positions = map[string]int{}
+	for i, row := range rows {
+		number := i + 1
+
+		menu, err := m.menu(ctx, row)
+		if err != nil {
+			return err
+		}
+		menuId := menu.Menu_id
+		if menuId == "" {
+			m.result.Errors = append(m.result.Errors, menuio.RowError{Row: number, Field: "menu_category", Error: "menu_category is required to create menu " + row.Menu})
+			continue
+		}
+
+		var categoryId string
+		for _, name := range menuio.SplitCategory(row.Category) {
+			if categoryId, err = m.category(ctx, menuId, categoryId, name); err != nil {
+				return err
+			}
+		}
+
+		if err := m.food(ctx, number, row, menu, categoryId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// returns the menu named by the row, creating it when missing.
+// An empty Menu_id means the menu is missing and the row cannot create it.
+func (m *menuImport) menu(ctx context.Context, row menuio.Row) (models.Menu, error) {
+	if menu, ok := m.menus[row.Menu]; ok {
+		return menu, nil
+	}
+
+	var menu models.Menu
+	err := menuCollection.FindOne(ctx, bson.M{"name": row.Menu}).Decode(&menu)
+	if err == nil {
+		m.menus[row.Menu] = menu
+		m.versioned[menu.Menu_id] = menu.Published_version_id != nil
+		return menu, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return models.Menu{}, err
+	}
+	if row.Menu_category == "" {
+		return models.Menu{}, nil
+	}
+
+	menu = models.Menu{Name: row.Menu, Category: row.Menu_category}
+	menu.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	menu.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	menu.ID = primitive.NewObjectID()
+	menu.Menu_id = menu.ID.Hex()
+	if m.apply {
+		if _, err := menuCollection.InsertOne(ctx, menu); err != nil {
+			return models.Menu{}, err
+		}
+	}
+	m.result.Menus_created++
+	m.menus[row.Menu] = menu
+	m.versioned[menu.Menu_id] = false
+	return menu, nil
+}
+
+// reports whether the menu has a published version, so that its foods change through drafts.
+func (m *menuImport) menuVersioned(ctx context.Context, menuId string) (bool, error) {
+	if versioned, ok := m.versioned[menuId]; ok {
+		return versioned, nil
+	}
+	var menu models.Menu
+	err := menuCollection.FindOne(ctx, bson.M{"menu_id": menuId}).Decode(&menu)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return false, err
+	}
+	m.versioned[menuId] = menu.Published_version_id != nil
+	return m.versioned[menuId], nil
+}
+
+// returns the ID of the category called name under parentId, creating it when missing.
+// New categories are positioned after their siblings in the order they first appear.
+func (m *menuImport) category(ctx context.Context, menuId string, parentId string, name string) (string, error) {
+	key := menuId + "/" + parentId + "/" + name
+	if id, ok := m.categories[key]; ok {
+		return id, nil
+	}
+
+	filter := bson.M{"menu_id": menuId, "name": name, "parent_id": nil}
+	if parentId != "" {
+		filter["parent_id"] = parentId
+	}
+	var category models.Category
+	err := categoryCollection.FindOne(ctx, filter).Decode(&category)
+	if err == nil {
+		m.categories[key] = category.Category_id
+		return category.Category_id, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return "", err
+	}
+
+	siblings := menuId + "/" + parentId
+	position := m.positions[siblings]
+	m.positions[siblings]++
+	category = models.Category{Name: &name, Menu_id: &menuId, Position: &position}
+	if parentId != "" {
+		category.Parent_id = &parentId
+	}
+	category.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	category.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	category.ID = primitive.NewObjectID()
+	category.Category_id = category.ID.Hex()
+	if m.apply {
+		if _, err := categoryCollection.InsertOne(ctx, category); err != nil {
+			return "", err
+		}
+	}
+	m.result.Categories_created++
+	m.categories[key] = category.Category_id
+	return category.Category_id, nil
+}
+
+// creates the food of the row, or updates the food that already has its SKU, or
+// whose food_id it is for foods that were exported without one. Foods of versioned
+// menus only take changes that leave what is served alone; the rest is a row error.
+func (m *menuImport) food(ctx context.Context, number int, row menuio.Row, menu models.Menu, categoryId string) error {
+	menuId := menu.Menu_id
+	price := toFixed(*row.Price, 2)
+	var description, foodImage, category *string
+	if row.Description != "" {
+		description = &row.Description
+	}
+	if row.Food_image != "" {
+		foodImage = &row.Food_image
+	}
+	if categoryId != "" {
+		category = &categoryId
+	}
+	tags := row.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	existing, err := m.findFood(ctx, bson.M{"sku": row.Sku})
+	if err == mongo.ErrNoDocuments {
+		existing, err = m.findFood(ctx, bson.M{"food_id": row.Sku})
+	}
+	if err != nil && err != mongo.ErrNoDocuments {
+		return err
+	}
+
+	if err == mongo.ErrNoDocuments {
+		food := models.Food{
+			Name:        &row.Name,
+			Description: description,
+			Tags:        tags,
+			Price:       &price,
+			Food_image:  foodImage,
+			Menu_id:     &menuId,
+			Category_id: category,
+			Position:    row.Position,
+			Sku:         &row.Sku,
+			Created_at:  now,
+			Updated_at:  now,
+			// On a versioned menu a new food is only served once a version with it is published.
+			Off_menu: menu.Published_version_id != nil,
+		}
+		food.ID = primitive.NewObjectID()
+		food.Food_id = food.ID.Hex()
+		m.result.Foods_created++
+		if !m.apply {
+			return nil
+		}
+		if _, err := foodCollection.InsertOne(ctx, food); err != nil {
+			return err
+		}
+		return m.recordPrice(ctx, models.PriceChange{Food_id: food.Food_id, New_price: price, Source: models.PriceSourceImport})
+	}
+
+	moved := existing.Menu_id == nil || *existing.Menu_id != menuId
+	if moved || importChangesContent(existing, row, price) {
+		for _, id := range []*string{existing.Menu_id, &menuId} {
+			if id == nil {
+				continue
+			}
+			versioned, err := m.menuVersioned(ctx, *id)
+			if err != nil {
+				return err
+			}
+			if versioned {
+				m.result.Errors = append(m.result.Errors, menuio.RowError{Row: number, Field: "sku",
+					Error: "food " + row.Sku + " is on a versioned menu, change it in a draft and publish it"})
+				return nil
+			}
+		}
+	}
+
+	m.result.Foods_updated++
+	if !m.apply {
+		return nil
+	}
+	set := bson.M{
+		"name":        row.Name,
+		"description": description,
+		"tags":        tags,
+		"price":       price,
+		"food_image":  foodImage,
+		"menu_id":     menuId,
+		"category_id": category,
+		"updated_at":  now,
+	}
+	if row.Position != nil {
+		set["position"] = row.Position
+	}
+	// The image variants belong to the uploaded image, a different image has none yet.
+	if stringValue(existing.Food_image) != row.Food_image {
+		set["images"] = nil
+	}
+	if _, err := foodCollection.UpdateOne(ctx, bson.M{"food_id": existing.Food_id}, bson.M{"$set": set}); err != nil {
+		return err
+	}
+	return m.recordPrice(ctx, models.PriceChange{Food_id: existing.Food_id, Old_price: existing.Price, New_price: price, Source: models.PriceSourceImport})
+}
+
+// reports whether importing the row would change what guests see of the food.
+func importChangesContent(food models.Food, row menuio.Row, price float64) bool {
+	if stringValue(food.Name) != row.Name || stringValue(food.Description) != row.Description ||
+		stringValue(food.Food_image) != row.Food_image || food.Price == nil || *food.Price != price {
+		return true
+	}
+	if len(food.Tags) != len(row.Tags) {
+		return true
+	}
+	for i, tag := range food.Tags {
+		if row.Tags[i] != tag {
+			return true
+		}
+	}
+	return false
+}
+
+// finds one food in foodCollection.
+func findFood(ctx context.Context, filter bson.M) (models.Food, error) {
+	var food models.Food
+	err := foodCollection.FindOne(ctx, filter).Decode(&food)
+	return food, err
+}
+
+func (m *menuImport) recordPrice(ctx context.Context, change models.PriceChange) error {
+	if err := recordPriceChange(ctx, m.c, change); err != nil {
+		return fmt.Errorf("recording the price of food %s: %w", change.Food_id, err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"golang-Restaurant-Management-backend/menuio"
+	"golang-Restaurant-Management-backend/models"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// finds foods in a slice by sku or food_id, the lookups the import makes.
+func foodFinder(foods []models.Food) func(ctx context.Context, filter bson.M) (models.Food, error) {
+	return func(ctx context.Context, filter bson.M) (models.Food, error) {
+		for _, food := range foods {
+			if sku, ok := filter["sku"]; ok && food.Sku != nil && *food.Sku == sku {
+				return food, nil
+			}
+			if id, ok := filter["food_id"]; ok && food.Food_id == id {
+				return food, nil
+			}
+		}
+		return models.Food{}, mongo.ErrNoDocuments
+	}
+}
+
+func TestImportExportedFoods(t *testing.T) {
+	name, description, price, sku := "Margherita", "Tomato and mozzarella", 12.0, "PIZ-1"
+	menuId, versionId := "m1", "v1"
+	unversioned := models.Menu{Menu_id: menuId, Name: "Dinner", Category: "Evening"}
+	versioned := unversioned
+	versioned.Published_version_id = &versionId
+
+	withoutSku := models.Food{Food_id: "f1", Name: &name, Description: &description, Tags: []string{}, Price: &price, Menu_id: &menuId}
+	withSku := withoutSku
+	withSku.Food_id, withSku.Sku = "f2", &sku
+
+	tests := []struct {
+		name        string
+		food        models.Food
+		menu        models.Menu
+		change      func(row *menuio.Row)
+		wantCreated int
+		wantUpdated int
+		wantErrors  int
+	}{
+		{"food without a SKU", withoutSku, unversioned, nil, 0, 1, 0},
+		{"food with a SKU", withSku, unversioned, nil, 0, 1, 0},
+		{"new SKU", withSku, unversioned, func(row *menuio.Row) { row.Sku = "PIZ-2" }, 1, 0, 0},
+		{"versioned menu, unchanged", withoutSku, versioned, nil, 0, 1, 0},
+		{"versioned menu, new price", withoutSku, versioned, func(row *menuio.Row) { *row.Price = 13 }, 0, 0, 1},
+		{"versioned menu, new name", withSku, versioned, func(row *menuio.Row) { row.Name = "Pizza Margherita" }, 0, 0, 1},
+		{"versioned menu, new food", withSku, versioned, func(row *menuio.Row) { row.Sku = "PIZ-2" }, 1, 0, 0},
+	}
+	for _, test := range tests {
+		// Export the food and read it back the way a re-import would.
+		var buf bytes.Buffer
+		if err := menuio.WriteCSV(&buf, []menuio.Row{exportRow(test.menu, "", test.food)}); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		rows, errs := menuio.ReadCSV(&buf)
+		if len(errs) > 0 || len(rows) != 1 {
+			t.Fatalf("%s: read %d rows, errors %+v", test.name, len(rows), errs)
+		}
+		if test.change != nil {
+			test.change(&rows[0])
+		}
+
+		m := &menuImport{
+			versioned: map[string]bool{menuId: test.menu.Published_version_id != nil},
+			findFood:  foodFinder([]models.Food{test.food}),
+		}
+		if err := m.food(context.Background(), 1, rows[0], test.menu, ""); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if m.result.Foods_created != test.wantCreated || m.result.Foods_updated != test.wantUpdated || len(m.result.Errors) != test.wantErrors {
+			t.Errorf("%s: created %d, updated %d, errors %+v; want %d, %d, %d errors", test.name,
+				m.result.Foods_created, m.result.Foods_updated, m.result.Errors, test.wantCreated, test.wantUpdated, test.wantErrors)
+		}
+	}
+}
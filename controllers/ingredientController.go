@@ -0,0 +1,389 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"golang-Restaurant-Management-backend/database"
+	"golang-Restaurant-Management-backend/models"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ingredientCollection *mongo.Collection = database.OpenCollection(database.Client, "ingredient")
+var stockAdjustmentCollection *mongo.Collection = database.OpenCollection(database.Client, "stock_adjustment")
+var stockAlertCollection *mongo.Collection = database.OpenCollection(database.Client, "stock_alert")
+
+func GetIngredients() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		result, err := ingredientCollection.Find(ctx, bson.M{})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while listing ingredients"})
+			return
+		}
+
+		var allIngredients []bson.M
+		if err = result.All(ctx, &allIngredients); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, allIngredients)
+	}
+}
+
+// GetLowStockIngredients lists ingredients at or below their low-stock threshold.
+func GetLowStockIngredients() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		filter := bson.M{"$expr": bson.M{"$lte": bson.A{"$stock", "$low_stock_threshold"}}, "low_stock_threshold": bson.M{"$ne": nil}}
+		result, err := ingredientCollection.Find(ctx, filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while listing low stock ingredients"})
+			return
+		}
+
+		var lowStock []bson.M
+		if err = result.All(ctx, &lowStock); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, lowStock)
+	}
+}
+
+func GetIngredient() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		ingredientId := c.Param("ingredient_id")
+		var ingredient models.Ingredient
+
+		err := ingredientCollection.FindOne(ctx, bson.M{"ingredient_id": ingredientId}).Decode(&ingredient)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while fetching the ingredient"})
+			return
+		}
+
+		c.JSON(http.StatusOK, ingredient)
+	}
+}
+
+func CreateIngredient() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var ingredient models.Ingredient
+
+		if err := c.BindJSON(&ingredient); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		validationErr := validate.Struct(ingredient)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		// Ingredients start empty unless an opening stock is given.
+		if ingredient.Stock == nil {
+			var zero float64
+			ingredient.Stock = &zero
+		}
+
+		ingredient.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		ingredient.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		ingredient.ID = primitive.NewObjectID()
+		ingredient.Ingredient_id = ingredient.ID.Hex()
+
+		result, insertErr := ingredientCollection.InsertOne(ctx, ingredient)
+		if insertErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ingredient was not created"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// UpdateIngredient changes the name, unit, threshold or par level. Stock only changes through adjustments.
+func UpdateIngredient() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var ingredient models.Ingredient
+		ingredientId := c.Param("ingredient_id")
+
+		if err := c.BindJSON(&ingredient); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var updateObj primitive.D
+
+		if ingredient.Name != nil {
+			updateObj = append(updateObj, bson.E{"name", ingredient.Name})
+		}
+		if ingredient.Unit != nil {
+			updateObj = append(updateObj, bson.E{"unit", ingredient.Unit})
+		}
+		if ingredient.Low_stock_threshold != nil {
+			updateObj = append(updateObj, bson.E{"low_stock_threshold", ingredient.Low_stock_threshold})
+		}
+		if ingredient.Par_level != nil {
+			updateObj = append(updateObj, bson.E{"par_level", ingredient.Par_level})
+		}
+
+		ingredient.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		updateObj = append(updateObj, bson.E{"updated_at", ingredient.Updated_at})
+
+		result, err := ingredientCollection.UpdateOne(
+			ctx,
+			bson.M{"ingredient_id": ingredientId},
+			bson.D{
+				{"$set", updateObj},
+			},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ingredient update failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// AdjustStock records a restock, a wastage or a stock count for an ingredient.
+// Restock quantities are added, wastage quantities are removed and a count sets the stock.
+func AdjustStock() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var adjustment models.StockAdjustment
+		ingredientId := c.Param("ingredient_id")
+
+		if err := c.BindJSON(&adjustment); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		validationErr := validate.Struct(adjustment)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+		if *adjustment.Quantity < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "quantity must not be negative"})
+			return
+		}
+		if adjustment.Unit_cost != nil && *adjustment.Type != models.StockRestock {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unit_cost is only accepted on a restock"})
+			return
+		}
+
+		adjustment.Ingredient_id = ingredientId
+		adjustment.Created_by = c.GetString("uid")
+
+		ingredient, err := applyStockAdjustment(ctx, adjustment)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, ingredient)
+	}
+}
+
+func GetStockAdjustments() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		ingredientId := c.Param("ingredient_id")
+
+		opts := options.Find().SetSort(bson.D{{"created_at", -1}})
+		result, err := stockAdjustmentCollection.Find(ctx, bson.M{"ingredient_id": ingredientId}, opts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while listing stock adjustments"})
+			return
+		}
+
+		var allAdjustments []bson.M
+		if err = result.All(ctx, &allAdjustments); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, allAdjustments)
+	}
+}
+
+// GetStockAlerts lists low-stock alerts, open ones by default.
+func GetStockAlerts() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		status := c.DefaultQuery("status", models.AlertOpen)
+		opts := options.Find().SetSort(bson.D{{"created_at", -1}})
+		result, err := stockAlertCollection.Find(ctx, bson.M{"status": status}, opts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while listing stock alerts"})
+			return
+		}
+
+		var allAlerts []bson.M
+		if err = result.All(ctx, &allAlerts); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, allAlerts)
+	}
+}
+
+// applies an adjustment to the ingredient's stock, records it and raises or
+// resolves the low-stock alert. The adjustment's Quantity is rewritten to the signed change.
+func applyStockAdjustment(ctx context.Context, adjustment models.StockAdjustment) (models.Ingredient, error) {
+	var ingredient models.Ingredient
+	err := ingredientCollection.FindOne(ctx, bson.M{"ingredient_id": adjustment.Ingredient_id}).Decode(&ingredient)
+	if err != nil {
+		return ingredient, fmt.Errorf("ingredient %s was not found", adjustment.Ingredient_id)
+	}
+
+	delta := *adjustment.Quantity
+	update := bson.M{}
+	switch *adjustment.Type {
+	case models.StockCount:
+		// A count replaces the stock, the recorded change is the difference.
+		current := 0.0
+		if ingredient.Stock != nil {
+			current = *ingredient.Stock
+		}
+		delta = *adjustment.Quantity - current
+		update["$set"] = bson.M{"stock": *adjustment.Quantity}
+	case models.StockWastage, models.StockDepletion:
+		delta = -delta
+		update["$inc"] = bson.M{"stock": delta}
+	default:
+		update["$inc"] = bson.M{"stock": delta}
+	}
+	updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	set, ok := update["$set"].(bson.M)
+	if !ok {
+		set = bson.M{}
+		update["$set"] = set
+	}
+	set["updated_at"] = updatedAt
+	// A restock with a known cost becomes the ingredient's current unit cost, other
+	// adjustments never change it.
+	if *adjustment.Type == models.StockRestock && adjustment.Unit_cost != nil {
+		set["unit_cost"] = *adjustment.Unit_cost
+	}
+
+	after := options.After
+	err = ingredientCollection.FindOneAndUpdate(
+		ctx,
+		bson.M{"ingredient_id": adjustment.Ingredient_id},
+		update,
+		&options.FindOneAndUpdateOptions{ReturnDocument: &after},
+	).Decode(&ingredient)
+	if err != nil {
+		return ingredient, fmt.Errorf("stock of ingredient %s was not updated", adjustment.Ingredient_id)
+	}
+
+	adjustment.Quantity = &delta
+	adjustment.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	adjustment.ID = primitive.NewObjectID()
+	adjustment.Adjustment_id = adjustment.ID.Hex()
+	if _, err = stockAdjustmentCollection.InsertOne(ctx, adjustment); err != nil {
+		return ingredient, fmt.Errorf("stock adjustment was not recorded")
+	}
+
+	return ingredient, checkStockAlert(ctx, ingredient)
+}
+
+// raises an alert when the ingredient is at or below its threshold and none is open yet,
+// and resolves the open alert once the stock is back above the threshold.
+func checkStockAlert(ctx context.Context, ingredient models.Ingredient) error {
+	if ingredient.Low_stock_threshold == nil || ingredient.Stock == nil {
+		return nil
+	}
+	now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	open := bson.M{"ingredient_id": ingredient.Ingredient_id, "status": models.AlertOpen}
+
+	if *ingredient.Stock > *ingredient.Low_stock_threshold {
+		_, err := stockAlertCollection.UpdateMany(ctx, open, bson.M{"$set": bson.M{"status": models.AlertResolved, "resolved_at": now}})
+		return err
+	}
+
+	count, err := stockAlertCollection.CountDocuments(ctx, open)
+	if err != nil || count > 0 {
+		return err
+	}
+
+	alert := models.StockAlert{
+		Ingredient_id:   ingredient.Ingredient_id,
+		Ingredient_name: *ingredient.Name,
+		Stock:           *ingredient.Stock,
+		Threshold:       *ingredient.Low_stock_threshold,
+		Status:          models.AlertOpen,
+		Created_at:      now,
+	}
+	alert.ID = primitive.NewObjectID()
+	alert.Alert_id = alert.ID.Hex()
+	_, err = stockAlertCollection.InsertOne(ctx, alert)
+	if err == nil {
+		log.Printf("low stock: %s is at %v %s", *ingredient.Name, *ingredient.Stock, *ingredient.Unit)
+	}
+	return err
+}
+
+// depletes the stock used by newly created order items according to their recipes.
+// Failures are logged rather than returned so that an inventory problem never blocks an order.
+func depleteStock(ctx context.Context, orderItems []models.OrderItem) {
+	moveRecipeStock(ctx, orderItems, models.StockDepletion)
+}
+
+// returnStock puts back the ingredients taken by order items that were voided before being made.
+func returnStock(ctx context.Context, orderItems []models.OrderItem) {
+	moveRecipeStock(ctx, orderItems, models.StockReturn)
+}
+
+// records a stock adjustment of stockType for every ingredient the recipes of the order items use.
+func moveRecipeStock(ctx context.Context, orderItems []models.OrderItem, stockType string) {
+	for _, orderItem := range orderItems {
+		if orderItem.Food_id == nil {
+			continue
+		}
+
+		usage, err := recipeUsage(ctx, *orderItem.Food_id, orderItem.Modifiers)
+		if err != nil {
+			log.Println("recipe lookup failed for food", *orderItem.Food_id, err)
+			continue
+		}
+
+		for ingredientId, quantity := range usage {
+			stockType := stockType
+			quantity := quantity
+			orderItemId := orderItem.Order_item_id
+			_, err := applyStockAdjustment(ctx, models.StockAdjustment{
+				Ingredient_id: ingredientId,
+				Type:          &stockType,
+				Quantity:      &quantity,
+				Order_item_id: &orderItemId,
+				Created_by:    "system",
+			})
+			if err != nil {
+				log.Println("stock "+strings.ToLower(stockType)+" failed for order item", orderItemId, err)
+			}
+		}
+	}
+}
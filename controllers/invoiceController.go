@@ -7,6 +7,7 @@ import (
 	"golang-Restaurant-Management-backend/models"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -22,6 +23,8 @@ type InvoiceViewFormat struct {
 	Order_id         string
 	Payment_status   *string
 	Payment_due      interface{}
+	Delivery_fee     interface{}
+	Order_type       interface{}
 	Table_number     interface{}
 	Payment_due_date time.Time
 	Order_details    interface{}
@@ -84,9 +87,13 @@ func GetInvoice() gin.HandlerFunc {
 		invoiceView.Payment_status = *&invoice.Payment_status
 
 		// Extract specific details from the first order item for the invoice view.
-		invoiceView.Payment_due = allOrderItems[0]["Payment_due"]
-		invoiceView.Table_number = allOrderItems[0]["table_number"]
-		invoiceView.Order_details = allOrderItems[0]["order_items"]
+		if len(allOrderItems) > 0 {
+			invoiceView.Payment_due = allOrderItems[0]["payment_due"]
+			invoiceView.Delivery_fee = allOrderItems[0]["delivery_fee"]
+			invoiceView.Order_type = allOrderItems[0]["order_type"]
+			invoiceView.Table_number = allOrderItems[0]["table_number"]
+			invoiceView.Order_details = allOrderItems[0]["order_items"]
+		}
 
 		// Return the invoice view as a JSON response.
 		c.JSON(http.StatusOK, invoiceView)
@@ -115,6 +122,10 @@ func CreateInvoice() gin.HandlerFunc {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
 			return
 		}
+		if order.Status == models.OrderStatusCancelled || order.Status == models.OrderStatusMerged {
+			c.JSON(http.StatusConflict, gin.H{"error": "a " + strings.ToLower(order.Status) + " order cannot be invoiced"})
+			return
+		}
 
 		// Default the payment status
 		status := "PENDING"
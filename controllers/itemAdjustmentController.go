@@ -0,0 +1,554 @@
+package controllers
+
+import (
+	"context"
+	"golang-Restaurant-Management-backend/database"
+	"golang-Restaurant-Management-backend/models"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var reasonCodeCollection *mongo.Collection = database.OpenCollection(database.Client, "reason_code")
+var itemAdjustmentCollection *mongo.Collection = database.OpenCollection(database.Client, "item_adjustment")
+
+// adjustmentFrom lists the item statuses each adjustment may be made from.
+var adjustmentFrom = map[string][]string{
+	models.AdjustmentVoid:   {models.OrderItemStatusHeld},
+	models.AdjustmentWaste:  {models.OrderItemStatusFired, models.OrderItemStatusReady, models.OrderItemStatusServed},
+	models.AdjustmentComp:   {models.OrderItemStatusHeld, models.OrderItemStatusFired, models.OrderItemStatusReady, models.OrderItemStatusServed},
+	models.AdjustmentRefire: {models.OrderItemStatusFired, models.OrderItemStatusReady, models.OrderItemStatusServed},
+}
+
+// the amount above which an adjustment by staff waits for a manager,
+// VOID_APPROVAL_THRESHOLD in the environment, 20 by default.
+func approvalThreshold() float64 {
+	if value, err := strconv.ParseFloat(os.Getenv("VOID_APPROVAL_THRESHOLD"), 64); err == nil && value >= 0 {
+		return value
+	}
+	return 20
+}
+
+// AdjustmentRequest is the body of a void, waste, comp or refire request.
+type AdjustmentRequest struct {
+	Reason_code string `json:"reason_code" validate:"required"`
+	Reason      string `json:"reason" validate:"max=500"`
+}
+
+// GetReasonCodes lists reason codes, filtered by ?action= and ?active=.
+func GetReasonCodes() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		filter := bson.M{}
+		if action := c.Query("action"); action != "" {
+			filter["actions"] = strings.ToUpper(action)
+		}
+		if active := c.Query("active"); active != "" {
+			if active == "true" {
+				filter["active"] = bson.M{"$ne": false}
+			} else {
+				filter["active"] = false
+			}
+		}
+
+		result, err := reasonCodeCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{"code", 1}}))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while listing reason codes"})
+			return
+		}
+
+		var allReasonCodes []bson.M
+		if err = result.All(ctx, &allReasonCodes); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, allReasonCodes)
+	}
+}
+
+func CreateReasonCode() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var reasonCode models.ReasonCode
+
+		if err := c.BindJSON(&reasonCode); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		validationErr := validate.Struct(reasonCode)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		code := strings.ToUpper(strings.TrimSpace(*reasonCode.Code))
+		count, err := reasonCodeCollection.CountDocuments(ctx, bson.M{"code": code})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while checking the reason code"})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Reason code " + code + " already exists"})
+			return
+		}
+
+		reasonCode.Code = &code
+		if reasonCode.Active == nil {
+			active := true
+			reasonCode.Active = &active
+		}
+		reasonCode.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		reasonCode.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		reasonCode.ID = primitive.NewObjectID()
+		reasonCode.Reason_code_id = reasonCode.ID.Hex()
+
+		result, insertErr := reasonCodeCollection.InsertOne(ctx, reasonCode)
+		if insertErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Reason code was not created"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// UpdateReasonCode changes the description, actions or active flag of a reason code.
+// Codes are kept once used, so retiring one means setting active to false.
+func UpdateReasonCode() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var reasonCode models.ReasonCode
+		reasonCodeId := c.Param("reason_code_id")
+
+		if err := c.BindJSON(&reasonCode); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var updateObj primitive.D
+		if reasonCode.Description != nil {
+			if validationErr := validate.StructPartial(reasonCode, "Description"); validationErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+				return
+			}
+			updateObj = append(updateObj, bson.E{"description", reasonCode.Description})
+		}
+		if reasonCode.Actions != nil {
+			if validationErr := validate.StructPartial(reasonCode, "Actions"); validationErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+				return
+			}
+			updateObj = append(updateObj, bson.E{"actions", reasonCode.Actions})
+		}
+		if reasonCode.Active != nil {
+			updateObj = append(updateObj, bson.E{"active", reasonCode.Active})
+		}
+		reasonCode.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		updateObj = append(updateObj, bson.E{"updated_at", reasonCode.Updated_at})
+
+		result, err := reasonCodeCollection.UpdateOne(
+			ctx,
+			bson.M{"reason_code_id": reasonCodeId},
+			bson.D{
+				{"$set", updateObj},
+			},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Reason code update failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// GetItemAdjustments lists adjustments, newest first, filtered by ?status=, ?type= or ?order_id=.
+func GetItemAdjustments() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		filter := bson.M{}
+		for _, param := range []string{"status", "type", "order_id"} {
+			if value := c.Query(param); value != "" {
+				filter[param] = value
+			}
+		}
+
+		result, err := itemAdjustmentCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{"created_at", -1}}))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while listing item adjustments"})
+			return
+		}
+
+		var allAdjustments []bson.M
+		if err = result.All(ctx, &allAdjustments); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, allAdjustments)
+	}
+}
+
+// AdjustOrderItem returns a handler voiding, waste-voiding, comping or refiring
+// an order item with a reason code. Adjustments worth more than the approval
+// threshold are applied straight away for managers; for other staff they are
+// stored as pending, answered with 202, until a manager approves them.
+func AdjustOrderItem(adjustmentType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var request AdjustmentRequest
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if validationErr := validate.Struct(request); validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		var orderItem models.OrderItem
+		if err := orderItemCollection.FindOne(ctx, bson.M{"order_item_id": c.Param("orderItem_id")}).Decode(&orderItem); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order item was not found"})
+			return
+		}
+
+		code := strings.ToUpper(strings.TrimSpace(request.Reason_code))
+		var reasonCode models.ReasonCode
+		if err := reasonCodeCollection.FindOne(ctx, bson.M{"code": code}).Decode(&reasonCode); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Reason code " + code + " was not found"})
+			return
+		}
+		if !reasonAllows(reasonCode, adjustmentType) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Reason code " + code + " cannot be used to " + strings.ToLower(adjustmentType)})
+			return
+		}
+
+		if err := checkAdjustment(ctx, orderItem, adjustmentType); err != nil {
+			respondStatusError(c, err)
+			return
+		}
+
+		adjustment := models.ItemAdjustment{Reason: request.Reason}
+		adjustment.ID = primitive.NewObjectID()
+		adjustment.Item_adjustment_id = adjustment.ID.Hex()
+		adjustment.Type = adjustmentType
+		adjustment.Reason_code = code
+		adjustment.Order_item_id = orderItem.Order_item_id
+		adjustment.Order_id = orderItem.Order_id
+		adjustment.Amount = adjustmentAmount(ctx, orderItem)
+		adjustment.Requested_by = c.GetString("uid")
+		adjustment.Requested_by_name = strings.TrimSpace(c.GetString("first_name") + " " + c.GetString("last_name"))
+		adjustment.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		adjustment.Updated_at = adjustment.Created_at
+
+		if adjustment.Amount > approvalThreshold() {
+			if !isManager(ctx, c) {
+				adjustment.Status = models.AdjustmentPendingApproval
+				if _, err := itemAdjustmentCollection.InsertOne(ctx, adjustment); err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Item adjustment was not recorded"})
+					return
+				}
+				c.JSON(http.StatusAccepted, adjustment)
+				return
+			}
+			approveAs(c, &adjustment)
+		}
+
+		if err := applyAdjustment(ctx, c, &adjustment, orderItem); err != nil {
+			respondStatusError(c, err)
+			return
+		}
+		adjustment.Status = models.AdjustmentApplied
+		if _, err := itemAdjustmentCollection.InsertOne(ctx, adjustment); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Item adjustment was not recorded"})
+			return
+		}
+
+		c.JSON(http.StatusOK, adjustment)
+	}
+}
+
+// ReviewItemAdjustment returns a handler with which a manager approves
+// (status APPLIED) or rejects a pending adjustment.
+func ReviewItemAdjustment(status string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		if !isManager(ctx, c) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only a manager can review item adjustments"})
+			return
+		}
+
+		var adjustment models.ItemAdjustment
+		if err := itemAdjustmentCollection.FindOne(ctx, bson.M{"item_adjustment_id": c.Param("item_adjustment_id")}).Decode(&adjustment); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item adjustment was not found"})
+			return
+		}
+		if adjustment.Status != models.AdjustmentPendingApproval {
+			c.JSON(http.StatusConflict, gin.H{"error": "the item adjustment is " + strings.ToLower(adjustment.Status) + ", it cannot be reviewed"})
+			return
+		}
+
+		var orderItem models.OrderItem
+		if status == models.AdjustmentApplied {
+			if err := orderItemCollection.FindOne(ctx, bson.M{"order_item_id": adjustment.Order_item_id}).Decode(&orderItem); err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Order item was not found"})
+				return
+			}
+			if err := checkAdjustment(ctx, orderItem, adjustment.Type); err != nil {
+				respondStatusError(c, err)
+				return
+			}
+		}
+
+		// Claim the adjustment before applying it, so that two managers
+		// reviewing it at once cannot both apply it.
+		approveAs(c, &adjustment)
+		update := bson.M{"$set": bson.M{
+			"status":           status,
+			"approved_by":      adjustment.Approved_by,
+			"approved_by_name": adjustment.Approved_by_name,
+			"approved_at":      adjustment.Approved_at,
+			"updated_at":       *adjustment.Approved_at,
+		}}
+		filter := bson.M{"item_adjustment_id": adjustment.Item_adjustment_id, "status": models.AdjustmentPendingApproval}
+		after := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		if err := itemAdjustmentCollection.FindOneAndUpdate(ctx, filter, update, after).Decode(&adjustment); err != nil {
+			if err == mongo.ErrNoDocuments {
+				c.JSON(http.StatusConflict, gin.H{"error": "the item adjustment was reviewed by someone else"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Item adjustment update failed"})
+			return
+		}
+
+		if status == models.AdjustmentApplied {
+			if err := applyAdjustment(ctx, c, &adjustment, orderItem); err != nil {
+				// Put the adjustment back up for review.
+				reopen := bson.M{
+					"$set":   bson.M{"status": models.AdjustmentPendingApproval, "updated_at": *adjustment.Approved_at},
+					"$unset": bson.M{"approved_by": "", "approved_by_name": "", "approved_at": ""},
+				}
+				if _, reopenErr := itemAdjustmentCollection.UpdateOne(ctx, bson.M{"item_adjustment_id": adjustment.Item_adjustment_id}, reopen); reopenErr != nil {
+					log.Println("item adjustment", adjustment.Item_adjustment_id, "was not reopened:", reopenErr)
+				}
+				respondStatusError(c, err)
+				return
+			}
+			if adjustment.Refire_order_item_id != nil {
+				refire := bson.M{"$set": bson.M{"refire_order_item_id": adjustment.Refire_order_item_id}}
+				if _, err := itemAdjustmentCollection.UpdateOne(ctx, bson.M{"item_adjustment_id": adjustment.Item_adjustment_id}, refire); err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Item adjustment update failed"})
+					return
+				}
+			}
+		}
+
+		c.JSON(http.StatusOK, adjustment)
+	}
+}
+
+// checks an adjustment may be made to the order item: its order still accepts
+// changes, the item is not part of a bundle and its status allows the adjustment.
+func checkAdjustment(ctx context.Context, orderItem models.OrderItem, adjustmentType string) error {
+	if err := checkOrderAcceptsItems(ctx, orderItem.Order_id); err != nil {
+		return err
+	}
+	if orderItem.Item_type == models.OrderItemTypeComponent {
+		return &conflictError{"the order item is part of a bundle, adjust the bundle instead"}
+	}
+	status := itemStatus(orderItem)
+	allowed := false
+	for _, from := range adjustmentFrom[adjustmentType] {
+		if status == from {
+			allowed = true
+		}
+	}
+	if !allowed {
+		return &conflictError{"an order item that is " + strings.ToLower(status) + " cannot be " + adjustmentVerbs[adjustmentType]}
+	}
+	if adjustmentType == models.AdjustmentComp && orderItem.Comped {
+		return &conflictError{"the order item is already comped"}
+	}
+
+	count, err := itemAdjustmentCollection.CountDocuments(ctx, bson.M{"order_item_id": orderItem.Order_item_id, "status": models.AdjustmentPendingApproval})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return &conflictError{"the order item has an adjustment waiting for approval"}
+	}
+	return nil
+}
+
+var adjustmentVerbs = map[string]string{
+	models.AdjustmentVoid:   "voided, waste it instead",
+	models.AdjustmentWaste:  "wasted, void it instead",
+	models.AdjustmentComp:   "comped",
+	models.AdjustmentRefire: "refired",
+}
+
+// applies an adjustment to the order item and its bundle items: voided items
+// give their ingredients back, wasted ones do not, comped ones are kept but not
+// charged, and a refire wastes the item and sends a copy to the kitchen.
+func applyAdjustment(ctx context.Context, c *gin.Context, adjustment *models.ItemAdjustment, orderItem models.OrderItem) error {
+	reason := adjustment.Reason_code
+	if adjustment.Reason != "" {
+		reason += ": " + adjustment.Reason
+	}
+
+	switch adjustment.Type {
+	case models.AdjustmentVoid:
+		if _, err := changeItemStatus(ctx, c, orderItem, models.OrderItemStatusVoided, reason); err != nil {
+			return err
+		}
+		returnStock(ctx, append([]models.OrderItem{orderItem}, bundleItems(ctx, orderItem)...))
+	case models.AdjustmentWaste:
+		if _, err := changeItemStatus(ctx, c, orderItem, models.OrderItemStatusWasted, reason); err != nil {
+			return err
+		}
+	case models.AdjustmentComp:
+		event := newOrderEvent(c, models.AdjustmentComp, "", "", reason)
+		filter := bson.M{"$or": bson.A{
+			bson.M{"order_item_id": orderItem.Order_item_id},
+			bson.M{"parent_order_item_id": orderItem.Order_item_id},
+		}}
+		update := bson.M{
+			"$set":  bson.M{"comped": true, "updated_at": event.At},
+			"$push": bson.M{"history": event},
+		}
+		if _, err := orderItemCollection.UpdateMany(ctx, filter, update); err != nil {
+			return err
+		}
+	case models.AdjustmentRefire:
+		if _, err := changeItemStatus(ctx, c, orderItem, models.OrderItemStatusWasted, reason); err != nil {
+			return err
+		}
+		refired, err := refireOrderItem(ctx, c, orderItem, reason)
+		if err != nil {
+			return err
+		}
+		adjustment.Refire_order_item_id = &refired.Order_item_id
+	}
+
+	publishTickets(ctx, orderItem.Order_id)
+	return nil
+}
+
+// inserts a fired copy of the order item, and of its bundle items, and takes
+// the ingredients for the copy out of stock.
+func refireOrderItem(ctx context.Context, c *gin.Context, orderItem models.OrderItem, reason string) (models.OrderItem, error) {
+	now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	event := newOrderEvent(c, models.OrderEventStatus, "", models.OrderItemStatusFired, reason)
+	refire := func(source models.OrderItem) models.OrderItem {
+		refireOf := source.Order_item_id
+		source.ID = primitive.NewObjectID()
+		source.Order_item_id = source.ID.Hex()
+		source.Status = models.OrderItemStatusFired
+		source.Fired_at = &now
+		source.Ready_at = nil
+		source.Served_at = nil
+		source.Refire_of = &refireOf
+		source.History = []models.OrderEvent{event}
+		source.Created_at = now
+		source.Updated_at = now
+		return source
+	}
+
+	refired := refire(orderItem)
+	copies := []models.OrderItem{refired}
+	for _, component := range bundleItems(ctx, orderItem) {
+		component = refire(component)
+		component.Parent_order_item_id = &refired.Order_item_id
+		copies = append(copies, component)
+	}
+
+	toInsert := []interface{}{}
+	for _, item := range copies {
+		toInsert = append(toInsert, item)
+	}
+	if _, err := orderItemCollection.InsertMany(ctx, toInsert); err != nil {
+		return refired, err
+	}
+	depleteStock(ctx, copies)
+	return refired, nil
+}
+
+// returns the component lines of a bundle line, none for any other item.
+func bundleItems(ctx context.Context, orderItem models.OrderItem) []models.OrderItem {
+	var components []models.OrderItem
+	if orderItem.Item_type != models.OrderItemTypeBundle {
+		return components
+	}
+	cursor, err := orderItemCollection.Find(ctx, bson.M{"parent_order_item_id": orderItem.Order_item_id})
+	if err != nil || cursor.All(ctx, &components) != nil {
+		log.Println("bundle items lookup failed for order item", orderItem.Order_item_id, err)
+	}
+	return components
+}
+
+// what the adjustment takes off the bill: the item's price plus any upcharges of its bundle items.
+func adjustmentAmount(ctx context.Context, orderItem models.OrderItem) float64 {
+	amount := 0.0
+	if orderItem.Unit_price != nil {
+		amount = *orderItem.Unit_price
+	} else if orderItem.Food_id != nil {
+		var food models.Food
+		if err := foodCollection.FindOne(ctx, bson.M{"food_id": orderItem.Food_id}).Decode(&food); err == nil && food.Price != nil {
+			amount = *food.Price
+		}
+	}
+	for _, component := range bundleItems(ctx, orderItem) {
+		if component.Unit_price != nil {
+			amount += *component.Unit_price
+		}
+	}
+	return toFixed(amount, 2)
+}
+
+// reason codes apply while active, to the actions they list.
+func reasonAllows(reasonCode models.ReasonCode, adjustmentType string) bool {
+	if reasonCode.Active != nil && !*reasonCode.Active {
+		return false
+	}
+	for _, action := range reasonCode.Actions {
+		if action == adjustmentType {
+			return true
+		}
+	}
+	return false
+}
+
+// records the signed-in user of c as approving the adjustment.
+func approveAs(c *gin.Context, adjustment *models.ItemAdjustment) {
+	approvedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	adjustment.Approved_by = c.GetString("uid")
+	adjustment.Approved_by_name = strings.TrimSpace(c.GetString("first_name") + " " + c.GetString("last_name"))
+	adjustment.Approved_at = &approvedAt
+}
+
+// reports whether the signed-in user is a manager.
+func isManager(ctx context.Context, c *gin.Context) bool {
+	var user models.User
+	if err := userCollection.FindOne(ctx, bson.M{"user_id": c.GetString("uid")}).Decode(&user); err != nil {
+		return false
+	}
+	return user.Role == models.UserRoleManager
+}
@@ -0,0 +1,434 @@
+package controllers
+
+import (
+	"context"
+	"golang-Restaurant-Management-backend/models"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Ticket is what a kitchen screen shows for an order: the items still to be
+// prepared or picked up, and how long the oldest of them has been waiting.
+type Ticket struct {
+	Ticket_id       string       `json:"ticket_id"`
+	Order_id        string       `json:"order_id"`
+	Order_type      string       `json:"order_type"`
+	Table_number    *int         `json:"table_number"`
+	Customer_name   *string      `json:"customer_name"`
+	Station_id      string       `json:"station_id"`
+	Opened_at       time.Time    `json:"opened_at"`
+	Elapsed_seconds int          `json:"elapsed_seconds"`
+	Has_allergy     bool         `json:"has_allergy"`
+	Notes           []TicketNote `json:"notes"`
+	Items           []TicketItem `json:"items"`
+}
+
+type TicketItem struct {
+	Order_item_id      string       `json:"order_item_id"`
+	Food_id            string       `json:"food_id"`
+	Name               string       `json:"name"`
+	Quantity           string       `json:"quantity"`
+	Modifiers          []string     `json:"modifiers"`
+	Status             string       `json:"status"`
+	Course             int          `json:"course"`
+	Fired_at           *time.Time   `json:"fired_at"`
+	Estimated_ready_at *time.Time   `json:"estimated_ready_at"`
+	Notes              []TicketNote `json:"notes"`
+}
+
+// TicketNote is a note of an order or order item as the kitchen sees it.
+type TicketNote struct {
+	Text        string `json:"text"`
+	Priority    string `json:"priority"`
+	Is_allergy  bool   `json:"is_allergy"`
+	Author_name string `json:"author_name"`
+}
+
+// ExpoOrder is the expeditor's view of an order: its tickets at every station
+// and how many of the items are ready to go out.
+type ExpoOrder struct {
+	Order_id        string    `json:"order_id"`
+	Order_type      string    `json:"order_type"`
+	Table_number    *int      `json:"table_number"`
+	Customer_name   *string   `json:"customer_name"`
+	Opened_at       time.Time `json:"opened_at"`
+	Elapsed_seconds int       `json:"elapsed_seconds"`
+	Items_total     int       `json:"items_total"`
+	Items_ready     int       `json:"items_ready"`
+	All_ready       bool      `json:"all_ready"`
+	Tickets         []Ticket  `json:"tickets"`
+}
+
+// TicketUpdate is pushed to kitchen screens whenever the items of an order change.
+// It carries every open ticket of the order; screens replace what they showed for
+// Order_id, so an empty list clears the order from the screen.
+type TicketUpdate struct {
+	Order_id string   `json:"order_id"`
+	Tickets  []Ticket `json:"tickets"`
+}
+
+// item statuses the kitchen still has to act on. Held items stay off the screens until fired.
+var kitchenStatuses = bson.A{models.OrderItemStatusFired, models.OrderItemStatusReady}
+
+// kitchenHub fans ticket updates out to the connected screens, each optionally
+// limited to one station.
+type kitchenHub struct {
+	mu      sync.Mutex
+	screens map[chan TicketUpdate]string
+}
+
+var kitchen = &kitchenHub{screens: map[chan TicketUpdate]string{}}
+
+// heartbeat keeps idle streams from being closed by proxies.
+const kitchenHeartbeat = 15 * time.Second
+
+func (h *kitchenHub) subscribe(station string) chan TicketUpdate {
+	screen := make(chan TicketUpdate, 64)
+	h.mu.Lock()
+	h.screens[screen] = station
+	h.mu.Unlock()
+	return screen
+}
+
+func (h *kitchenHub) unsubscribe(screen chan TicketUpdate) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.screens[screen]; ok {
+		delete(h.screens, screen)
+		close(screen)
+	}
+}
+
+// publish sends the update to every screen. A screen that has fallen too far
+// behind is disconnected; it replays the open tickets when it reconnects.
+func (h *kitchenHub) publish(update TicketUpdate) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for screen, station := range h.screens {
+		select {
+		case screen <- TicketUpdate{Order_id: update.Order_id, Tickets: ticketsForStation(update.Tickets, station)}:
+		default:
+			delete(h.screens, screen)
+			close(screen)
+		}
+	}
+}
+
+// GetKitchenTickets returns the open tickets, oldest first, optionally for one ?station=.
+func GetKitchenTickets() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tickets, err := openTickets(ctx, bson.M{})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while listing the kitchen tickets"})
+			return
+		}
+		c.JSON(http.StatusOK, ticketsForStation(tickets, c.Query("station")))
+	}
+}
+
+// GetExpoOrders returns every order with open kitchen items, or only ?order_id=,
+// with the tickets of all its stations side by side.
+func GetExpoOrders() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		filter := bson.M{}
+		if orderId := c.Query("order_id"); orderId != "" {
+			filter["order_id"] = orderId
+		}
+		tickets, err := openTickets(ctx, filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while listing the kitchen tickets"})
+			return
+		}
+
+		orders := []*ExpoOrder{}
+		byOrder := map[string]*ExpoOrder{}
+		for _, ticket := range tickets {
+			order, ok := byOrder[ticket.Order_id]
+			if !ok {
+				order = &ExpoOrder{Order_id: ticket.Order_id, Order_type: ticket.Order_type, Table_number: ticket.Table_number, Customer_name: ticket.Customer_name,
+					Opened_at: ticket.Opened_at, Tickets: []Ticket{}}
+				byOrder[ticket.Order_id] = order
+				orders = append(orders, order)
+			}
+			if ticket.Opened_at.Before(order.Opened_at) {
+				order.Opened_at = ticket.Opened_at
+			}
+			order.Tickets = append(order.Tickets, ticket)
+			for _, item := range ticket.Items {
+				order.Items_total++
+				if item.Status == models.OrderItemStatusReady {
+					order.Items_ready++
+				}
+			}
+		}
+		now := time.Now()
+		for _, order := range orders {
+			order.Elapsed_seconds = int(now.Sub(order.Opened_at).Seconds())
+			order.All_ready = order.Items_total > 0 && order.Items_ready == order.Items_total
+		}
+
+		c.JSON(http.StatusOK, orders)
+	}
+}
+
+// StreamKitchenTickets streams tickets to a kitchen screen as Server-Sent Events.
+// A "snapshot" event with every open ticket is sent on connect, so a screen that
+// reconnects catches up, followed by a "tickets" event for each order that changes.
+func StreamKitchenTickets() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		station := c.Query("station")
+
+		// Subscribe before reading the snapshot so no change falls in between.
+		screen := kitchen.subscribe(station)
+		defer kitchen.unsubscribe(screen)
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		tickets, err := openTickets(ctx, bson.M{})
+		cancel()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while listing the kitchen tickets"})
+			return
+		}
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("X-Accel-Buffering", "no")
+		c.SSEvent("snapshot", ticketsForStation(tickets, station))
+		c.Writer.Flush()
+
+		heartbeat := time.NewTicker(kitchenHeartbeat)
+		defer heartbeat.Stop()
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case update, ok := <-screen:
+				if !ok {
+					return false
+				}
+				c.SSEvent("tickets", update)
+				return true
+			case now := <-heartbeat.C:
+				c.SSEvent("ping", now.Format(time.RFC3339))
+				return true
+			case <-c.Request.Context().Done():
+				return false
+			}
+		})
+	}
+}
+
+// BumpOrderItem marks a fired item ready.
+func BumpOrderItem() gin.HandlerFunc {
+	return kitchenAction(func(ctx context.Context, c *gin.Context, orderItem models.OrderItem) (models.OrderItem, error) {
+		return changeItemStatus(ctx, c, orderItem, models.OrderItemStatusReady, "")
+	})
+}
+
+// RecallOrderItem sends a ready item back to the kitchen.
+func RecallOrderItem() gin.HandlerFunc {
+	return kitchenAction(func(ctx context.Context, c *gin.Context, orderItem models.OrderItem) (models.OrderItem, error) {
+		return changeItemStatus(ctx, c, orderItem, models.OrderItemStatusFired, "")
+	})
+}
+
+// runs a status change on the item in the URL and pushes the order's tickets to the screens.
+func kitchenAction(action func(ctx context.Context, c *gin.Context, orderItem models.OrderItem) (models.OrderItem, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var orderItem models.OrderItem
+		if err := orderItemCollection.FindOne(ctx, bson.M{"order_item_id": c.Param("orderItem_id")}).Decode(&orderItem); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order item was not found"})
+			return
+		}
+
+		orderItem, err := action(ctx, c, orderItem)
+		if err != nil {
+			respondStatusError(c, err)
+			return
+		}
+		publishTickets(ctx, orderItem.Order_id)
+
+		c.JSON(http.StatusOK, orderItem)
+	}
+}
+
+// pushes the current tickets of an order to the kitchen screens.
+func publishTickets(ctx context.Context, orderId string) {
+	moved, err := refreshReadyEstimates(ctx, orderId)
+	if err != nil {
+		log.Println("estimating the orders in the kitchen failed:", err)
+	}
+	for _, id := range append([]string{orderId}, moved...) {
+		publishOrderTickets(ctx, id)
+	}
+}
+
+func publishOrderTickets(ctx context.Context, orderId string) {
+	tickets, err := openTickets(ctx, bson.M{"order_id": orderId})
+	if err != nil {
+		log.Println("building the kitchen tickets of order", orderId, "failed:", err)
+		return
+	}
+	kitchen.publish(TicketUpdate{Order_id: orderId, Tickets: tickets})
+}
+
+// builds the tickets of the open kitchen items matching filter, one per order
+// and station, oldest first.
+// Bundle lines are left out: the kitchen prepares their components.
+func openTickets(ctx context.Context, filter bson.M) ([]Ticket, error) {
+	filter["status"] = bson.M{"$in": kitchenStatuses}
+	filter["item_type"] = bson.M{"$ne": models.OrderItemTypeBundle}
+
+	var orderItems []models.OrderItem
+	cursor, err := orderItemCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{"created_at", 1}}))
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(ctx, &orderItems); err != nil {
+		return nil, err
+	}
+
+	foodIds := bson.A{}
+	orderIds := bson.A{}
+	orderItemIds := bson.A{}
+	for _, orderItem := range orderItems {
+		if orderItem.Food_id != nil {
+			foodIds = append(foodIds, *orderItem.Food_id)
+		}
+		orderIds = append(orderIds, orderItem.Order_id)
+		orderItemIds = append(orderItemIds, orderItem.Order_item_id)
+	}
+
+	orderNotes, err := notesFor(ctx, models.NoteOnOrder, orderIds)
+	if err != nil {
+		return nil, err
+	}
+	itemNotes, err := notesFor(ctx, models.NoteOnOrderItem, orderItemIds)
+	if err != nil {
+		return nil, err
+	}
+
+	foodNames := map[string]string{}
+	var foods []models.Food
+	if cursor, err = foodCollection.Find(ctx, bson.M{"food_id": bson.M{"$in": foodIds}}); err != nil {
+		return nil, err
+	}
+	if err = cursor.All(ctx, &foods); err != nil {
+		return nil, err
+	}
+	for _, food := range foods {
+		foodNames[food.Food_id] = stringValue(food.Name)
+	}
+
+	tableNumbers := map[string]*int{}
+	ordersById := map[string]models.Order{}
+	var orders []models.Order
+	if cursor, err = orderCollection.Find(ctx, bson.M{"order_id": bson.M{"$in": orderIds}}); err != nil {
+		return nil, err
+	}
+	if err = cursor.All(ctx, &orders); err != nil {
+		return nil, err
+	}
+	for _, order := range orders {
+		ordersById[order.Order_id] = order
+		if order.Table_id == nil {
+			continue
+		}
+		var table models.Table
+		if err := tableCollection.FindOne(ctx, bson.M{"table_id": order.Table_id}).Decode(&table); err == nil {
+			tableNumbers[order.Order_id] = table.Table_number
+		}
+	}
+
+	now := time.Now()
+	tickets := []Ticket{}
+	byId := map[string]int{}
+	for _, orderItem := range orderItems {
+		item := TicketItem{
+			Order_item_id:      orderItem.Order_item_id,
+			Food_id:            stringValue(orderItem.Food_id),
+			Name:               foodNames[stringValue(orderItem.Food_id)],
+			Quantity:           stringValue(orderItem.Quantity),
+			Modifiers:          orderItem.Modifiers,
+			Status:             itemStatus(orderItem),
+			Course:             itemCourse(orderItem),
+			Fired_at:           orderItem.Fired_at,
+			Estimated_ready_at: orderItem.Estimated_ready_at,
+			Notes:              ticketNotes(itemNotes[orderItem.Order_item_id]),
+		}
+		opened := orderItem.Created_at
+		if item.Fired_at != nil {
+			opened = *item.Fired_at
+		}
+
+		// Each order gets one ticket per station it needs.
+		station := stringValue(orderItem.Station_id)
+		ticketId := orderItem.Order_id
+		if station != "" {
+			ticketId += ":" + station
+		}
+		i, ok := byId[ticketId]
+		if !ok {
+			i = len(tickets)
+			byId[ticketId] = i
+			order := ordersById[orderItem.Order_id]
+			tickets = append(tickets, Ticket{Ticket_id: ticketId, Order_id: orderItem.Order_id, Order_type: orderType(order), Table_number: tableNumbers[orderItem.Order_id],
+				Customer_name: order.Customer_name, Station_id: station, Opened_at: opened, Notes: ticketNotes(orderNotes[orderItem.Order_id]), Items: []TicketItem{}})
+		}
+		ticket := &tickets[i]
+		if opened.Before(ticket.Opened_at) {
+			ticket.Opened_at = opened
+		}
+		ticket.Items = append(ticket.Items, item)
+		for _, note := range ticket.Notes {
+			ticket.Has_allergy = ticket.Has_allergy || note.Is_allergy
+		}
+		for _, note := range item.Notes {
+			ticket.Has_allergy = ticket.Has_allergy || note.Is_allergy
+		}
+	}
+
+	for i := range tickets {
+		tickets[i].Elapsed_seconds = int(now.Sub(tickets[i].Opened_at).Seconds())
+	}
+	sort.SliceStable(tickets, func(i, j int) bool { return tickets[i].Opened_at.Before(tickets[j].Opened_at) })
+	return tickets, nil
+}
+
+func ticketNotes(notes []models.Note) []TicketNote {
+	ticketNotes := []TicketNote{}
+	for _, note := range notes {
+		ticketNotes = append(ticketNotes, TicketNote{Text: note.Text, Priority: note.Priority, Is_allergy: note.Is_allergy, Author_name: note.Author_name})
+	}
+	return ticketNotes
+}
+
+// keeps the tickets of one station; every ticket when station is empty.
+// Items of foods without a station rule only show on the all-stations view.
+func ticketsForStation(tickets []Ticket, station string) []Ticket {
+	if station == "" {
+		return tickets
+	}
+	filtered := []Ticket{}
+	for _, ticket := range tickets {
+		if ticket.Station_id == station {
+			filtered = append(filtered, ticket)
+		}
+	}
+	return filtered
+}
@@ -34,6 +34,10 @@ func GetMenus() gin.HandlerFunc {
 		if err = result.All(ctx, &allMenus); err != nil {
 			log.Fatal(err)
 		}
+		locales := requestLocales(c)
+		for _, menu := range allMenus {
+			localizeDoc(menu, locales)
+		}
 
 		// Send the list of all menus as a JSON format.
 		c.JSON(http.StatusOK, allMenus)
@@ -48,12 +52,14 @@ func GetMenu() gin.HandlerFunc {
 		var menu models.Menu
 
 		// Find the menu with the menu_id in the database and decode the result.
-		err := foodCollection.FindOne(ctx, bson.M{"menu_id": menuId}).Decode(&menu)
+		err := menuCollection.FindOne(ctx, bson.M{"menu_id": menuId}).Decode(&menu)
 		defer cancel()
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while fetching the menu"})
+			return
 		}
 
+		localizeMenu(&menu, requestLocales(c))
 		c.JSON(http.StatusOK, menu)
 	}
 }
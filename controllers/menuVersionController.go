@@ -0,0 +1,370 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"golang-Restaurant-Management-backend/database"
+	"golang-Restaurant-Management-backend/models"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var menuVersionCollection *mongo.Collection = database.OpenCollection(database.Client, "menu_version")
+
+var errVersionNotPublishable = errors.New("only draft or scheduled menu versions can be published")
+
+// GetMenuVersions lists every version of a menu, newest first, so past menus stay visible.
+func GetMenuVersions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		menuId := c.Param("menu_id")
+
+		opts := options.Find().SetSort(bson.D{{"version", -1}})
+		result, err := menuVersionCollection.Find(ctx, bson.M{"menu_id": menuId}, opts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while listing menu versions"})
+			return
+		}
+
+		var allVersions []bson.M
+		if err = result.All(ctx, &allVersions); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, allVersions)
+	}
+}
+
+// GetMenuVersion returns one version with its items, which is how drafts are previewed.
+func GetMenuVersion() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		versionId := c.Param("menu_version_id")
+		var version models.MenuVersion
+
+		err := menuVersionCollection.FindOne(ctx, bson.M{"menu_version_id": versionId}).Decode(&version)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while fetching the menu version"})
+			return
+		}
+
+		c.JSON(http.StatusOK, version)
+	}
+}
+
+// CreateMenuDraft starts a new draft of a menu from the foods as they are live now.
+// Foods dropped from the menu can be put back by editing the draft.
+func CreateMenuDraft() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var menu models.Menu
+		menuId := c.Param("menu_id")
+
+		err := menuCollection.FindOne(ctx, bson.M{"menu_id": menuId}).Decode(&menu)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Menu was not found"})
+			return
+		}
+
+		cursor, err := foodCollection.Find(ctx, bson.M{"menu_id": menuId, "off_menu": bson.M{"$ne": true}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while listing the menu's food items"})
+			return
+		}
+		var foods []models.Food
+		if err = cursor.All(ctx, &foods); err != nil {
+			log.Fatal(err)
+		}
+
+		version := models.MenuVersion{Menu_id: menuId, Status: models.MenuVersionDraft, Items: []models.MenuVersionItem{}}
+		for _, food := range foods {
+			version.Items = append(version.Items, models.MenuVersionItem{
+				Food_id:     food.Food_id,
+				Name:        food.Name,
+				Description: food.Description,
+				Tags:        food.Tags,
+				Price:       food.Price,
+				Food_image:  food.Food_image,
+			})
+		}
+
+		// Versions are numbered per menu.
+		var latest models.MenuVersion
+		opts := options.FindOne().SetSort(bson.D{{"version", -1}})
+		if err := menuVersionCollection.FindOne(ctx, bson.M{"menu_id": menuId}, opts).Decode(&latest); err == nil {
+			version.Version = latest.Version + 1
+		} else {
+			version.Version = 1
+		}
+
+		version.Created_by = c.GetString("uid")
+		version.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		version.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		version.ID = primitive.NewObjectID()
+		version.Menu_version_id = version.ID.Hex()
+
+		_, insertErr := menuVersionCollection.InsertOne(ctx, version)
+		if insertErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Menu draft was not created"})
+			return
+		}
+
+		c.JSON(http.StatusOK, version)
+	}
+}
+
+// UpdateMenuDraft replaces the items of a draft. Live foods are not touched until the draft is published.
+func UpdateMenuDraft() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var draft models.MenuVersion
+		var version models.MenuVersion
+		versionId := c.Param("menu_version_id")
+
+		if err := c.BindJSON(&draft); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if validationErr := validate.Var(draft.Items, "required,dive"); validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		err := menuVersionCollection.FindOne(ctx, bson.M{"menu_version_id": versionId}).Decode(&version)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Menu version was not found"})
+			return
+		}
+
+		// Every item must be a food of this menu.
+		for i, item := range draft.Items {
+			count, err := foodCollection.CountDocuments(ctx, bson.M{"food_id": item.Food_id, "menu_id": version.Menu_id})
+			if err != nil || count == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Food " + item.Food_id + " is not on this menu"})
+				return
+			}
+			price := toFixed(*item.Price, 2)
+			draft.Items[i].Price = &price
+		}
+
+		updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		filter := bson.M{"menu_version_id": versionId, "status": models.MenuVersionDraft}
+		result, err := menuVersionCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"items": draft.Items, "updated_at": updatedAt}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Menu draft update failed"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Only drafts can be edited"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// PublishMenuVersion makes a draft live now, or schedules it when publish_at is in the future.
+func PublishMenuVersion() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var request struct {
+			Publish_at *time.Time `json:"publish_at"`
+		}
+		versionId := c.Param("menu_version_id")
+
+		if c.Request.ContentLength != 0 {
+			if err := c.BindJSON(&request); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		if request.Publish_at != nil && request.Publish_at.After(time.Now()) {
+			updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+			filter := bson.M{"menu_version_id": versionId, "status": bson.M{"$in": bson.A{models.MenuVersionDraft, models.MenuVersionScheduled}}}
+			update := bson.M{"$set": bson.M{"status": models.MenuVersionScheduled, "publish_at": request.Publish_at, "published_by": c.GetString("uid"), "updated_at": updatedAt}}
+			result, err := menuVersionCollection.UpdateOne(ctx, filter, update)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Menu version was not scheduled"})
+				return
+			}
+			if result.MatchedCount == 0 {
+				c.JSON(http.StatusConflict, gin.H{"error": errVersionNotPublishable.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"status": models.MenuVersionScheduled, "publish_at": request.Publish_at})
+			return
+		}
+
+		err := publishMenuVersion(ctx, versionId, c.GetString("uid"))
+		if errors.Is(err, errVersionNotPublishable) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Menu version was not published"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"status": models.MenuVersionPublished})
+	}
+}
+
+// publishes a version in one transaction: the version goes live, the previous one
+// is archived, the foods take the version's content (recording any price change),
+// the menu's foods left out of it go off the menu and the menu points at it.
+// Either all of it is visible to ordering or none of it.
+// Transactions need MongoDB to run as a replica set.
+func publishMenuVersion(ctx context.Context, versionId string, publishedBy string) error {
+	session, err := database.Client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		var version models.MenuVersion
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		filter := bson.M{"menu_version_id": versionId, "status": bson.M{"$in": bson.A{models.MenuVersionDraft, models.MenuVersionScheduled}}}
+		set := bson.M{"status": models.MenuVersionPublished, "published_at": now, "updated_at": now}
+		if publishedBy != "" {
+			set["published_by"] = publishedBy
+		}
+		after := options.After
+		err := menuVersionCollection.FindOneAndUpdate(sc, filter, bson.M{"$set": set}, &options.FindOneAndUpdateOptions{ReturnDocument: &after}).Decode(&version)
+		if err == mongo.ErrNoDocuments {
+			return nil, errVersionNotPublishable
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = menuVersionCollection.UpdateMany(sc,
+			bson.M{"menu_id": version.Menu_id, "status": models.MenuVersionPublished, "menu_version_id": bson.M{"$ne": versionId}},
+			bson.M{"$set": bson.M{"status": models.MenuVersionArchived, "updated_at": now}})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range version.Items {
+			var food models.Food
+			if err := foodCollection.FindOne(sc, bson.M{"food_id": item.Food_id}).Decode(&food); err != nil {
+				return nil, err
+			}
+			err := recordPriceChange(sc, nil, models.PriceChange{
+				Food_id:         item.Food_id,
+				Old_price:       food.Price,
+				New_price:       *item.Price,
+				Source:          models.PriceSourceMenuVersion,
+				Menu_version_id: &version.Menu_version_id,
+				Changed_by:      version.Published_by,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			set := bson.M{
+				"name":        item.Name,
+				"description": item.Description,
+				"tags":        item.Tags,
+				"price":       item.Price,
+				"food_image":  item.Food_image,
+				"off_menu":    false,
+				"updated_at":  now,
+			}
+			// The resized images belong to the live image.
+			if stringValue(item.Food_image) != stringValue(food.Food_image) {
+				set["images"] = nil
+			}
+			_, err = foodCollection.UpdateOne(sc,
+				bson.M{"food_id": item.Food_id, "menu_id": version.Menu_id},
+				bson.M{"$set": set})
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		served := bson.A{}
+		for _, item := range version.Items {
+			served = append(served, item.Food_id)
+		}
+		_, err = foodCollection.UpdateMany(sc,
+			bson.M{"menu_id": version.Menu_id, "food_id": bson.M{"$nin": served}},
+			bson.M{"$set": bson.M{"off_menu": true, "updated_at": now}})
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = menuCollection.UpdateOne(sc,
+			bson.M{"menu_id": version.Menu_id},
+			bson.M{"$set": bson.M{"published_version_id": version.Menu_version_id, "published_version": version.Version, "updated_at": now}})
+		return nil, err
+	})
+	return err
+}
+
+// PublishScheduledMenus publishes every scheduled version whose time has come.
+func PublishScheduledMenus(ctx context.Context) {
+	filter := bson.M{"status": models.MenuVersionScheduled, "publish_at": bson.M{"$lte": time.Now()}}
+	cursor, err := menuVersionCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{"publish_at", 1}}))
+	if err != nil {
+		log.Println("listing scheduled menu versions failed:", err)
+		return
+	}
+	var due []models.MenuVersion
+	if err = cursor.All(ctx, &due); err != nil {
+		log.Println("listing scheduled menu versions failed:", err)
+		return
+	}
+
+	for _, version := range due {
+		if err := publishMenuVersion(ctx, version.Menu_version_id, ""); err != nil {
+			log.Println("publishing scheduled menu version", version.Menu_version_id, "failed:", err)
+		}
+	}
+}
+
+// StartScheduler runs the scheduled jobs once a minute in the background.
+func StartScheduler() {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Second)
+			PublishScheduledMenus(ctx)
+			ReleaseScheduledOrders(ctx)
+			cancel()
+		}
+	}()
+}
+
+// refuses direct edits to the foods of a menu that has a published version.
+func checkMenuUnversioned(ctx context.Context, menuId string) error {
+	var menu models.Menu
+	if err := menuCollection.FindOne(ctx, bson.M{"menu_id": menuId}).Decode(&menu); err == nil && menu.Published_version_id != nil {
+		return &conflictError{"the menu is versioned, change its foods in a draft and publish it"}
+	}
+	return nil
+}
+
+// returns the published version of a menu, the one new order items are taken against.
+func publishedMenuVersion(ctx context.Context, menuId string) *string {
+	var menu models.Menu
+	if err := menuCollection.FindOne(ctx, bson.M{"menu_id": menuId}).Decode(&menu); err != nil {
+		return nil
+	}
+	return menu.Published_version_id
+}
@@ -0,0 +1,229 @@
+package controllers
+
+import (
+	"context"
+	"golang-Restaurant-Management-backend/database"
+	"golang-Restaurant-Management-backend/models"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var noteCollection *mongo.Collection = database.OpenCollection(database.Client, "note")
+
+// noteTargets maps each kind of note to the collection and ID field of what it is attached to.
+var noteTargets = map[string]struct {
+	collection *mongo.Collection
+	idField    string
+}{
+	models.NoteOnOrder:     {orderCollection, "order_id"},
+	models.NoteOnOrderItem: {orderItemCollection, "order_item_id"},
+	models.NoteOnTable:     {tableCollection, "table_id"},
+	models.NoteOnInvoice:   {invoiceCollection, "invoice_id"},
+}
+
+// high priority notes first, then oldest first.
+var noteOrder = options.Find().SetSort(bson.D{{"priority_rank", 1}, {"created_at", 1}})
+
+// GetNotes returns a handler listing the notes attached to the record named by
+// the URL parameter param, e.g. the notes of an order.
+func GetNotes(entityType string, param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		filter := bson.M{"entity_type": entityType, "entity_id": c.Param(param)}
+		result, err := noteCollection.Find(ctx, filter, noteOrder)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while listing notes"})
+			return
+		}
+
+		var allNotes []bson.M
+		if err = result.All(ctx, &allNotes); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, allNotes)
+	}
+}
+
+func GetNote() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var note models.Note
+
+		err := noteCollection.FindOne(ctx, bson.M{"note_id": c.Param("note_id")}).Decode(&note)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while fetching the note"})
+			return
+		}
+
+		c.JSON(http.StatusOK, note)
+	}
+}
+
+// CreateNote returns a handler attaching a note to the record named by the URL
+// parameter param. The author is the signed-in user. Allergy notes are always
+// high priority, and notes on orders and their items show on the kitchen tickets.
+func CreateNote(entityType string, param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var note models.Note
+
+		if err := c.BindJSON(&note); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		note.Entity_type = entityType
+		note.Entity_id = c.Param(param)
+		if note.Priority == "" || note.Is_allergy {
+			note.Priority = models.NotePriorityNormal
+			if note.Is_allergy {
+				note.Priority = models.NotePriorityHigh
+			}
+		}
+		validationErr := validate.Struct(note)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		target := noteTargets[entityType]
+		count, err := target.collection.CountDocuments(ctx, bson.M{target.idField: note.Entity_id})
+		if err != nil || count == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": strings.ToLower(strings.ReplaceAll(entityType, "_", " ")) + " was not found"})
+			return
+		}
+
+		note.Priority_rank = models.NotePriorityRank(note.Priority)
+		note.Author_id = c.GetString("uid")
+		note.Author_name = strings.TrimSpace(c.GetString("first_name") + " " + c.GetString("last_name"))
+		note.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		note.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		note.ID = primitive.NewObjectID()
+		note.Note_id = note.ID.Hex()
+
+		if _, insertErr := noteCollection.InsertOne(ctx, note); insertErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Note was not created"})
+			return
+		}
+		publishNoteTickets(ctx, note)
+
+		c.JSON(http.StatusOK, note)
+	}
+}
+
+// UpdateNote changes the text, title, priority or allergy flag of a note. A note
+// that is, or becomes, an allergy note stays high priority.
+func UpdateNote() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var note models.Note
+		var update struct {
+			Text       *string `json:"text" validate:"omitempty,min=1,max=1000"`
+			Title      *string `json:"title" validate:"omitempty,max=100"`
+			Priority   *string `json:"priority" validate:"omitempty,eq=NORMAL|eq=HIGH"`
+			Is_allergy *bool   `json:"is_allergy"`
+		}
+
+		if err := c.BindJSON(&update); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if validationErr := validate.Struct(update); validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		var stored models.Note
+		if err := noteCollection.FindOne(ctx, bson.M{"note_id": c.Param("note_id")}).Decode(&stored); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Note was not found"})
+			return
+		}
+
+		set := bson.M{}
+		if update.Text != nil {
+			set["text"] = update.Text
+		}
+		if update.Title != nil {
+			set["title"] = update.Title
+		}
+		if update.Priority != nil || update.Is_allergy != nil {
+			priority := stored.Priority
+			if update.Priority != nil {
+				priority = *update.Priority
+			}
+			isAllergy := stored.Is_allergy
+			if update.Is_allergy != nil {
+				isAllergy = *update.Is_allergy
+				set["is_allergy"] = isAllergy
+			}
+			if isAllergy {
+				priority = models.NotePriorityHigh
+			}
+			set["priority"] = priority
+			set["priority_rank"] = models.NotePriorityRank(priority)
+		}
+		set["updated_at"], _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		// The allergy flag must not have changed since it was read.
+		filter := bson.M{"note_id": stored.Note_id, "is_allergy": stored.Is_allergy}
+		after := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		err := noteCollection.FindOneAndUpdate(ctx, filter, bson.M{"$set": set}, after).Decode(&note)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusConflict, gin.H{"error": "the note was changed by someone else, try again"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Note update failed"})
+			return
+		}
+		publishNoteTickets(ctx, note)
+
+		c.JSON(http.StatusOK, note)
+	}
+}
+
+// refreshes the kitchen screens when a note of an order or order item changes.
+func publishNoteTickets(ctx context.Context, note models.Note) {
+	switch note.Entity_type {
+	case models.NoteOnOrder:
+		publishTickets(ctx, note.Entity_id)
+	case models.NoteOnOrderItem:
+		var orderItem models.OrderItem
+		if err := orderItemCollection.FindOne(ctx, bson.M{"order_item_id": note.Entity_id}).Decode(&orderItem); err == nil {
+			publishTickets(ctx, orderItem.Order_id)
+		}
+	}
+}
+
+// returns the notes attached to the given records, keyed by record ID, high priority first.
+func notesFor(ctx context.Context, entityType string, ids bson.A) (map[string][]models.Note, error) {
+	notes := map[string][]models.Note{}
+	if len(ids) == 0 {
+		return notes, nil
+	}
+	var found []models.Note
+	cursor, err := noteCollection.Find(ctx, bson.M{"entity_type": entityType, "entity_id": bson.M{"$in": ids}}, noteOrder)
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(ctx, &found); err != nil {
+		return nil, err
+	}
+	for _, note := range found {
+		notes[note.Entity_id] = append(notes[note.Entity_id], note)
+	}
+	return notes, nil
+}
@@ -7,6 +7,7 @@ import (
 	"golang-Restaurant-Management-backend/models"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -23,8 +24,15 @@ func GetOrders() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
 
-		// Query the orderCollection to find all documents.
-		result, err := orderCollection.Find(context.TODO(), bson.M{})
+		// Query the orderCollection to find all documents, or only those with ?status=.
+		filter := bson.M{}
+		if status := c.Query("status"); status != "" {
+			filter["status"] = statusFilter(status)
+		}
+		if orderType := c.Query("order_type"); orderType != "" {
+			filter["order_type"] = orderTypeFilter(orderType)
+		}
+		result, err := orderCollection.Find(context.TODO(), filter)
 		defer cancel()
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while listing order items"})
@@ -60,6 +68,8 @@ func GetOrder() gin.HandlerFunc {
 
 func CreateOrder() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 		var table models.Table
 		var order models.Order
 
@@ -69,17 +79,31 @@ func CreateOrder() gin.HandlerFunc {
 			return
 		}
 
+		// Orders without a type are eaten in.
+		if order.Order_type == "" {
+			order.Order_type = models.OrderTypeDineIn
+		}
+
 		// Validate the order struct
 		validationErr := validate.Struct(order)
 		if validationErr != nil{
 			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
 			return
 		}
+		if err := checkOrderType(order); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if order.Scheduled_for != nil {
+			if err := checkScheduledFor(ctx, *order.Scheduled_for); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
 		
 		// Validate whether the specific tableId in order exist in database
 		if order.Table_id != nil{
 			err := tableCollection.FindOne(ctx, bson.M{"table_id": order.Table_id}).Decode(&table)
-			defer cancel()
 			if err != nil{
 				msg := fmt.Sprintf("message:Table was not found")
 				c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
@@ -87,6 +111,11 @@ func CreateOrder() gin.HandlerFunc {
 			}
 		}
 
+		// New orders start open, or scheduled when wanted later, whatever the client sent.
+		order.Status = newOrderStatus(order)
+		order.History = []models.OrderEvent{}
+		order.Release_at = initialRelease(order)
+
 		// Set the creation and update timestamps 
 		order.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		order.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
@@ -102,7 +131,6 @@ func CreateOrder() gin.HandlerFunc {
 			c.JSON(http.StatusInternalServerError, gin.H{"error":msg})
 			return
 		}
-		defer cancel()
 
 		// Return the result of the insertion.
 		c.JSON(http.StatusOK, result)
@@ -137,6 +165,46 @@ func UpdateOrder() gin.HandlerFunc {
 			updateObj = append(updateObj, bson.E{"menu", order.Table_id})
 		}
 
+		// Customer and delivery details are checked against the order's type as they will be stored.
+		if order.Customer_name != nil || order.Customer_phone != nil || order.Delivery_address != nil || order.Delivery_fee != nil || order.Promised_at != nil {
+			var stored models.Order
+			if err := orderCollection.FindOne(ctx, bson.M{"order_id": orderId}).Decode(&stored); err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Order was not found"})
+				return
+			}
+			if stored.Order_type == "" {
+				stored.Order_type = models.OrderTypeDineIn
+			}
+			if order.Customer_name != nil {
+				stored.Customer_name = order.Customer_name
+				updateObj = append(updateObj, bson.E{"customer_name", order.Customer_name})
+			}
+			if order.Customer_phone != nil {
+				stored.Customer_phone = order.Customer_phone
+				updateObj = append(updateObj, bson.E{"customer_phone", order.Customer_phone})
+			}
+			if order.Delivery_address != nil {
+				stored.Delivery_address = order.Delivery_address
+				updateObj = append(updateObj, bson.E{"delivery_address", order.Delivery_address})
+			}
+			if order.Delivery_fee != nil {
+				stored.Delivery_fee = order.Delivery_fee
+				updateObj = append(updateObj, bson.E{"delivery_fee", order.Delivery_fee})
+			}
+			if order.Promised_at != nil {
+				stored.Promised_at = order.Promised_at
+				updateObj = append(updateObj, bson.E{"promised_at", order.Promised_at})
+			}
+			if validationErr := validate.StructPartial(stored, "Customer_name", "Customer_phone", "Delivery_address", "Delivery_fee"); validationErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+				return
+			}
+			if err := checkOrderType(stored); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
 		// Update the updated_at timestamp.
 		order.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		updateObj = append(updateObj, bson.E{"updated_at", order.Updated_at})
@@ -174,19 +242,92 @@ func UpdateOrder() gin.HandlerFunc {
 }
 
 // use to create a  new orderID and return it
-func OrderItemOrderCreator(order models.Order) string{
+func OrderItemOrderCreator(ctx context.Context, order models.Order) (string, error) {
+	order.Status = newOrderStatus(order)
+	order.History = []models.OrderEvent{}
+	order.Release_at = initialRelease(order)
+
 	// Set the created and updated timestamps
 	order.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 	order.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 
-	// Generate a new unique ObjectID for the order and set it as the order's ID.
-	order.ID = primitive.NewObjectID()
-	order.Order_id = order.ID.Hex()
+	// Generate a new unique ObjectID for the order and set it as the order's ID, unless the caller already did.
+	if order.Order_id == "" {
+		order.ID = primitive.NewObjectID()
+		order.Order_id = order.ID.Hex()
+	}
 
 	// Insert the new order into the database.
-	orderCollection.InsertOne(ctx, order)
-	defer cancel()
+	if _, err := orderCollection.InsertOne(ctx, order); err != nil {
+		return "", err
+	}
 
 	// Return the newly created order ID.
-	return order.Order_id
+	return order.Order_id, nil
+}
+
+// checks the order has the details its type needs: a customer to call for
+// takeout, and an address as well for delivery. Only delivery orders have a
+// delivery address or fee, and only orders not eaten in have a promised time.
+func checkOrderType(order models.Order) error {
+	missing := []string{}
+	if order.Order_type == models.OrderTypeTakeout || order.Order_type == models.OrderTypeDelivery {
+		if order.Customer_name == nil || strings.TrimSpace(*order.Customer_name) == "" {
+			missing = append(missing, "customer_name")
+		}
+		if order.Customer_phone == nil || strings.TrimSpace(*order.Customer_phone) == "" {
+			missing = append(missing, "customer_phone")
+		}
+	}
+	if order.Order_type == models.OrderTypeDelivery && (order.Delivery_address == nil || strings.TrimSpace(*order.Delivery_address) == "") {
+		missing = append(missing, "delivery_address")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%s orders need %s", orderTypeName(order.Order_type), strings.Join(missing, ", "))
+	}
+
+	if order.Order_type != models.OrderTypeDelivery && (order.Delivery_address != nil || order.Delivery_fee != nil) {
+		return fmt.Errorf("only delivery orders have a delivery address or fee")
+	}
+	if order.Promised_at != nil {
+		if order.Order_type == models.OrderTypeDineIn {
+			return fmt.Errorf("dine-in orders have no promised time")
+		}
+		if order.Promised_at.Before(time.Now()) {
+			return fmt.Errorf("promised_at must be in the future")
+		}
+	}
+	return nil
+}
+
+// the order type as written in messages, e.g. "drive-through".
+func orderTypeName(orderType string) string {
+	return strings.ReplaceAll(strings.ToLower(orderType), "_", "-")
+}
+
+// matches the stored type of an order, counting orders without one as dine-in.
+func orderTypeFilter(orderType string) interface{} {
+	orderType = strings.ToUpper(orderType)
+	if orderType == models.OrderTypeDineIn {
+		return bson.M{"$in": bson.A{models.OrderTypeDineIn, nil}}
+	}
+	return orderType
+}
+
+// the status a new order starts in: scheduled when it is wanted later, open otherwise.
+func newOrderStatus(order models.Order) string {
+	if order.Scheduled_for != nil {
+		return models.OrderStatusScheduled
+	}
+	return models.OrderStatusOpen
+}
+
+// a scheduled order without items yet is released the buffer before it is due;
+// adding items moves the release earlier by their prep time.
+func initialRelease(order models.Order) *time.Time {
+	if order.Scheduled_for == nil {
+		return nil
+	}
+	releaseAt := order.Scheduled_for.Add(-releaseBuffer())
+	return &releaseAt
 }
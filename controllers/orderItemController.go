@@ -17,8 +17,15 @@ import (
 )
 
 type OrderItemPack struct {
-	Table_id    *string
-	Order_items []models.OrderItem
+	Table_id         *string
+	Order_type       string
+	Customer_name    *string
+	Customer_phone   *string
+	Delivery_address *string
+	Delivery_fee     *float64
+	Promised_at      *time.Time
+	Scheduled_for    *time.Time
+	Order_items      []models.OrderItem
 }
 
 var orderItemCollection *mongo.Collection = database.OpenCollection(database.Client, "OrderItem")
@@ -47,11 +54,11 @@ func GetOrderItems() gin.HandlerFunc {
 func GetOrderItem() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-		orderItemId := c.Param("order_item_id")
+		orderItemId := c.Param("orderItem_id")
 		var orderItem models.OrderItem
 
 	  // Find the order item in the database using the provided order_item_id.
-		err := orderItemCollection.FindOne(ctx, bson.M{"orderItem_id": orderItemId}).Decode(&orderItem)
+		err := orderItemCollection.FindOne(ctx, bson.M{"order_item_id": orderItemId}).Decode(&orderItem)
 		defer cancel()
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while listing ordered item"})
@@ -93,6 +100,10 @@ func ItemsByOrder(id string) (OrderItems []primitive.M, err error) {
 	// {"preserveNullAndEmptyArrays", true}: default is false
 	unwindStage := bson.D{{"$unwind", bson.D{{"path", "$food"}, {"preserveNullAndEmptyArrays", true}}}}
 
+	// bundle lines have no food, so look up the bundle for their name and image
+	lookupBundleStage := bson.D{{"$lookup", bson.D{{"from", "bundle"}, {"localField", "bundle_id"}, {"foreignField", "bundle_id"}, {"as", "bundle"}}}}
+	unwindBundleStage := bson.D{{"$unwind", bson.D{{"path", "$bundle"}, {"preserveNullAndEmptyArrays", true}}}}
+
 	lookupOrderStage := bson.D{{"$lookup", bson.D{{"from", "order"}, {"localField", "order_id"}, {"foreignField", "order_id"}, {"as", "order"}}}}
 	unwindOrderStage := bson.D{{"$unwind", bson.D{{"path", "$order"}, {"preserveNullAndEmptyArrays", true}}}}
 
@@ -101,31 +112,70 @@ func ItemsByOrder(id string) (OrderItems []primitive.M, err error) {
 
 	// projectStage: to manage the fields that you'll be turning to the frontend, means controls what goes to the next stage
 	// because after we process the above(mathch, lookup, unwind), we will get lots of fields and data that not required, they might confuse the frontend
+	// bundle and component lines are priced by what was stored on the line (bundle price, upcharge),
+	// plain food lines by the price stored when ordered, or the food's price for lines stored without one
+	isBundleLine := bson.D{{"$in", bson.A{"$item_type", bson.A{models.OrderItemTypeBundle, models.OrderItemTypeComponent}}}}
+	linePrice := bson.D{{"$cond", bson.A{isBundleLine, "$unit_price", bson.D{{"$ifNull", bson.A{"$unit_price", "$food.price"}}}}}}
+	// voided, wasted and comped lines stay on the order but are not charged
+	isNotCharged := bson.D{{"$or", bson.A{
+		bson.D{{"$in", bson.A{"$status", bson.A{models.OrderItemStatusVoided, models.OrderItemStatusWasted}}}},
+		bson.D{{"$eq", bson.A{"$comped", true}}},
+	}}}
+	lineAmount := bson.D{{"$cond", bson.A{isNotCharged, 0, linePrice}}}
 	projectStage := bson.D{
 		{"$project", bson.D{
-			{"id", 0},                 // 0 means do not goes to next stage
-			{"amount", "$food.price"}, // which send to frontend and refer to price in Food model
-			{"total_count", 1},        // 1 means should go to the frontend
-			{"food_name", "$food.name"},
-			{"food_image", "$food.food_image"},
+			{"id", 0},             // 0 means do not goes to next stage
+			{"amount", lineAmount}, // which send to frontend and refer to price in Food model
+			{"total_count", 1},    // 1 means should go to the frontend
+			{"food_name", bson.D{{"$ifNull", bson.A{"$food.name", "$bundle.name"}}}},
+			{"food_image", bson.D{{"$ifNull", bson.A{"$food.food_image", "$bundle.food_image"}}}},
 			{"table_number", "$table.table_number"},
 			{"table_id", "$table.table_id"},
 			{"order_id", "$order.order_id"},
-			{"price", "$food.price"},
+			{"order_type", "$order.order_type"},
+			{"delivery_fee", "$order.delivery_fee"},
+			{"order_item_id", 1},
+			{"item_type", 1},
+			{"status", bson.D{{"$ifNull", bson.A{"$status", models.OrderItemStatusHeld}}}},
+			{"course", bson.D{{"$ifNull", bson.A{"$course", 1}}}},
+			{"comped", bson.D{{"$ifNull", bson.A{"$comped", false}}}},
+			{"bundle_id", 1},
+			{"parent_order_item_id", 1},
+			{"price", linePrice},
 			{"quantity", 1},
+			{"nutrition", bson.D{{"$ifNull", bson.A{"$nutrition", "$food.nutrition"}}}},
 		}}}
 
 	// groupStage : group all the data based on particular parameters
-	groupStage := bson.D{{"$group", bson.D{{"_id", bson.D{{"order_id", "$order_id"}, {"table_id", "$table_id"}, {"table_number", "$table_number"}}}, {"payment_due", bson.D{{"$sum", "$amount"}}}, {"total_count", bson.D{{"$sum", 1}}}, {"order_items", bson.D{{"$push", "$$ROOT"}}}}}}
+	groupStage := bson.D{{"$group", bson.D{{"_id", bson.D{{"order_id", "$order_id"}, {"table_id", "$table_id"}, {"table_number", "$table_number"}}}, {"payment_due", bson.D{{"$sum", "$amount"}}}, {"total_count", bson.D{{"$sum", 1}}}, {"order_items", bson.D{{"$push", "$$ROOT"}}},
+		{"order_type", bson.D{{"$first", "$order_type"}}},
+		{"delivery_fee", bson.D{{"$first", "$delivery_fee"}}},
+		{"calories", bson.D{{"$sum", "$nutrition.calories"}}},
+		{"protein_g", bson.D{{"$sum", "$nutrition.protein_g"}}},
+		{"carbs_g", bson.D{{"$sum", "$nutrition.carbs_g"}}},
+		{"fat_g", bson.D{{"$sum", "$nutrition.fat_g"}}},
+		{"sodium_mg", bson.D{{"$sum", "$nutrition.sodium_mg"}}},
+	}}}
 
 	projectStage2 := bson.D{
 		{"$project", bson.D{
 
 			{"id", 0},
-			{"payment_due", 1},
+			// delivery orders also pay the delivery fee
+			{"payment_due", bson.D{{"$add", bson.A{"$payment_due", bson.D{{"$ifNull", bson.A{"$delivery_fee", 0}}}}}}},
+			{"delivery_fee", bson.D{{"$ifNull", bson.A{"$delivery_fee", 0}}}},
+			{"order_type", bson.D{{"$ifNull", bson.A{"$order_type", models.OrderTypeDineIn}}}},
 			{"total_count", 1},
 			{"table_number", "$_id.table_number"},
 			{"order_items", 1},
+			// nutrition totals of the whole order
+			{"nutrition", bson.D{
+				{"calories", "$calories"},
+				{"protein_g", "$protein_g"},
+				{"carbs_g", "$carbs_g"},
+				{"fat_g", "$fat_g"},
+				{"sodium_mg", "$sodium_mg"},
+			}},
 		}}}
 
 	// Execute an aggregation pipeline to fetch and format order items.
@@ -133,6 +183,8 @@ func ItemsByOrder(id string) (OrderItems []primitive.M, err error) {
 		matchStage,
 		lookupStage,
 		unwindStage,
+		lookupBundleStage,
+		unwindBundleStage,
 		lookupOrderStage,
 		unwindOrderStage,
 		lookupTableStage,
@@ -160,6 +212,7 @@ func ItemsByOrder(id string) (OrderItems []primitive.M, err error) {
 func CreateOrderItem() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 
 		var orderItemPack OrderItemPack
 		var order models.Order
@@ -173,51 +226,243 @@ func CreateOrderItem() gin.HandlerFunc {
 		// Set the order date to the current time.
 		order.Order_Date, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 
-		// Initialize a slice to hold the order items for batch insertion.（批量插入）
-		orderItemToBeInserted := []interface{}{}
-		// Assign the table ID from the order item pack to the order.
+		// Assign the table ID, or the customer for orders not eaten in, from the order item pack to the order.
 		order.Table_id = orderItemPack.Table_id
-		// Create a new order and get its ID.
-		order_id := OrderItemOrderCreator(order)
-
-		// Iterate over the order items to process each one.
-		for _, orderItem := range orderItemPack.Order_items {
-			// Assign the generated order ID to each order item
-			orderItem.Order_id = order_id
-
-			// Validate the structure of each order item.
-			validationErr := validate.Struct(orderItem)
-			if validationErr != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+		order.Order_type = orderItemPack.Order_type
+		if order.Order_type == "" {
+			order.Order_type = models.OrderTypeDineIn
+		}
+		order.Customer_name = orderItemPack.Customer_name
+		order.Customer_phone = orderItemPack.Customer_phone
+		order.Delivery_address = orderItemPack.Delivery_address
+		order.Delivery_fee = orderItemPack.Delivery_fee
+		order.Promised_at = orderItemPack.Promised_at
+		order.Scheduled_for = orderItemPack.Scheduled_for
+		if validationErr := validate.Struct(order); validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+		if err := checkOrderType(order); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if order.Scheduled_for != nil {
+			if err := checkScheduledFor(ctx, *order.Scheduled_for); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 				return
 			}
+		}
+		if len(orderItemPack.Order_items) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "order_items must not be empty"})
+			return
+		}
 
-			// Generate a unique ID for each order item and set the created and updated timestamps.
-			orderItem.ID = primitive.NewObjectID()
-			orderItem.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-			orderItem.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-			orderItem.Order_item_id = orderItem.ID.Hex()
-
-			// Round the unit price to two decimal places.
-			var num = toFixed(*orderItem.Unit_price, 2)
-			orderItem.Unit_price = &num
-			// Add the order item to the slice for batch insertion.
-			orderItemToBeInserted = append(orderItemToBeInserted, orderItem)
+		// A table's items go on its open order, if it has one; otherwise on a new order,
+		// which is only stored once its items are known to be valid.
+		openOrder, hasOpenOrder := openTableOrder(ctx, order)
+		if hasOpenOrder {
+			order = openOrder
+		} else {
+			order.ID = primitive.NewObjectID()
+			order.Order_id = order.ID.Hex()
 		}
 
-		// Insert all the order items into the database at once.
-		insertedOrderItems, err := orderItemCollection.InsertMany(ctx, orderItemToBeInserted)
+		orderItems, err := prepareOrderItems(ctx, order.Order_id, orderItemPack.Order_items)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if order.Scheduled_for != nil {
+			if err := checkMenusServe(ctx, orderItems, *order.Scheduled_for); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if !hasOpenOrder {
+			if _, err := OrderItemOrderCreator(ctx, order); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Order was not created"})
+				return
+			}
+		}
 
+		insertedOrderItems, err := insertOrderItems(ctx, orderItems)
 		if err != nil {
-			log.Fatal(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Order items were not created"})
+			return
 		}
+		if err := scheduleRelease(ctx, order.Order_id); err != nil {
+			log.Println("release time of order", order.Order_id, "was not set:", err)
+		}
+		estimatedReadyAt := storeOrderEstimate(ctx, order.Order_id)
+
+		c.JSON(http.StatusOK, OrderItemsCreated{insertedOrderItems, order.Order_id, estimatedReadyAt})
+	}
+}
+
+// OrderItemsCreated answers a request creating order items: the ids of the new
+// items, their order and when it is expected to be ready.
+type OrderItemsCreated struct {
+	*mongo.InsertManyResult
+	Order_id           string     `json:"order_id"`
+	Estimated_ready_at *time.Time `json:"estimated_ready_at"`
+}
+
+// OrderItemsAddition is the body of a request adding items to an order.
+type OrderItemsAddition struct {
+	Order_items []models.OrderItem `json:"order_items"`
+}
 
+// AddOrderItems appends items to an order that still accepts them, such as a
+// second round of drinks for a table.
+func AddOrderItems() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
 		defer cancel()
+		orderId := c.Param("order_id")
 
-		c.JSON(http.StatusOK, insertedOrderItems)
+		var addition OrderItemsAddition
+		if err := c.BindJSON(&addition); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if len(addition.Order_items) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "order_items must not be empty"})
+			return
+		}
+
+		var order models.Order
+		if err := orderCollection.FindOne(ctx, bson.M{"order_id": orderId}).Decode(&order); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order was not found"})
+			return
+		}
+		if err := checkOrderAcceptsItems(ctx, orderId); err != nil {
+			respondStatusError(c, err)
+			return
+		}
+
+		orderItems, err := prepareOrderItems(ctx, orderId, addition.Order_items)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if order.Status == models.OrderStatusScheduled && order.Scheduled_for != nil {
+			if err := checkMenusServe(ctx, orderItems, *order.Scheduled_for); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		insertedOrderItems, err := insertOrderItems(ctx, orderItems)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Order items were not created"})
+			return
+		}
+		if err := scheduleRelease(ctx, orderId); err != nil {
+			log.Println("release time of order", orderId, "was not set:", err)
+		}
+		estimatedReadyAt := storeOrderEstimate(ctx, orderId)
+
+		c.JSON(http.StatusOK, OrderItemsCreated{insertedOrderItems, orderId, estimatedReadyAt})
 	}
 }
 
+// returns the order the table is eating from: its newest order that still accepts items.
+// Orders not eaten in, and orders scheduled for later, are never shared.
+func openTableOrder(ctx context.Context, order models.Order) (models.Order, bool) {
+	var openOrder models.Order
+	if order.Order_type != models.OrderTypeDineIn || order.Table_id == nil || order.Scheduled_for != nil {
+		return openOrder, false
+	}
+	filter := bson.M{
+		"table_id":   order.Table_id,
+		"status":     bson.M{"$in": bson.A{models.OrderStatusOpen, models.OrderStatusSent, models.OrderStatusServed, nil}},
+		"order_type": orderTypeFilter(models.OrderTypeDineIn),
+	}
+	opts := options.FindOne().SetSort(bson.D{{"created_at", -1}})
+	if err := orderCollection.FindOne(ctx, filter, opts).Decode(&openOrder); err != nil {
+		return openOrder, false
+	}
+	return openOrder, true
+}
+
+// validates and prices the items for an order, expanding bundles into their lines.
+// Prices and costs come from the menu, whatever the client sent.
+func prepareOrderItems(ctx context.Context, orderId string, requested []models.OrderItem) ([]models.OrderItem, error) {
+	orderItems := []models.OrderItem{}
+	for _, orderItem := range requested {
+		orderItem.Order_id = orderId
+
+		// Validate the structure of each order item.
+		if validationErr := validate.Struct(orderItem); validationErr != nil {
+			return nil, validationErr
+		}
+
+		// Items go with the first course unless told otherwise.
+		if orderItem.Course == nil {
+			firstCourse := 1
+			orderItem.Course = &firstCourse
+		}
+
+		// A bundle becomes a priced bundle line plus one component line per slot.
+		if orderItem.Bundle_id != nil {
+			bundleItems, err := expandBundle(ctx, orderItem)
+			if err != nil {
+				return nil, err
+			}
+			snapshotBundleCost(ctx, bundleItems)
+			for _, bundleItem := range bundleItems {
+				bundleItem.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+				bundleItem.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+				bundleItem.Status = models.OrderItemStatusHeld
+				bundleItem.History = []models.OrderEvent{}
+				orderItems = append(orderItems, bundleItem)
+			}
+			continue
+		}
+
+		// Check the food exists and remember which menu version it was ordered from.
+		var food models.Food
+		if err := foodCollection.FindOne(ctx, bson.M{"food_id": orderItem.Food_id}).Decode(&food); err != nil {
+			return nil, fmt.Errorf("Food %s was not found", *orderItem.Food_id)
+		}
+		if food.Off_menu {
+			return nil, fmt.Errorf("%s is not on the menu", stringValue(food.Name))
+		}
+		orderItem.Menu_version_id = publishedMenuVersion(ctx, *food.Menu_id)
+
+		// Generate a unique ID for each order item and set the created and updated timestamps.
+		orderItem.ID = primitive.NewObjectID()
+		orderItem.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		orderItem.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		orderItem.Order_item_id = orderItem.ID.Hex()
+		orderItem.Item_type = models.OrderItemTypeFood
+		orderItem.Status = models.OrderItemStatusHeld
+		orderItem.History = []models.OrderEvent{}
+		orderItem.Unit_cost = snapshotCost(ctx, *orderItem.Food_id, orderItem.Modifiers)
+		orderItem.Nutrition = food.Nutrition
+		orderItem.Station_id = routeStation(ctx, food, orderItem.Modifiers)
+
+		// Charge the food's current price, whatever the client sent.
+		var num = toFixed(*food.Price, 2)
+		orderItem.Unit_price = &num
+		orderItems = append(orderItems, orderItem)
+	}
+	return orderItems, nil
+}
+
+// inserts the order items at once and takes the ingredients they use out of stock.
+func insertOrderItems(ctx context.Context, orderItems []models.OrderItem) (*mongo.InsertManyResult, error) {
+	orderItemToBeInserted := []interface{}{}
+	for _, orderItem := range orderItems {
+		orderItemToBeInserted = append(orderItemToBeInserted, orderItem)
+	}
+	insertedOrderItems, err := orderItemCollection.InsertMany(ctx, orderItemToBeInserted)
+	if err != nil {
+		return nil, err
+	}
+	depleteStock(ctx, orderItems)
+	return insertedOrderItems, nil
+}
+
 func UpdateOrderItem() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
@@ -226,10 +471,7 @@ func UpdateOrderItem() gin.HandlerFunc {
 
 		var updateObj primitive.D
 
-		// Append the update field
-		if orderItem.Unit_price != nil {
-			updateObj = append(updateObj, bson.E{"unit_price", *&orderItem.Unit_price})
-		}
+		// Append the update field. The unit price is set from the food when the item is created.
 		if orderItem.Quantity != nil {
 			updateObj = append(updateObj, bson.E{"quantity", *&orderItem.Quantity})
 		}
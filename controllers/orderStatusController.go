@@ -0,0 +1,418 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"golang-Restaurant-Management-backend/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// StatusChange is the optional body of a transition request.
+type StatusChange struct {
+	Reason string `json:"reason"`
+}
+
+// CourseFiring is the result of firing a course.
+type CourseFiring struct {
+	Order  models.Order `json:"order"`
+	Course int          `json:"course"`
+	Fired  int64        `json:"fired"`
+}
+
+// orderItemCascade lists, for an order status, which item statuses move along
+// with the order and where to: serving serves what the kitchen is working on,
+// cancelling voids held items and wastes those already made. Sending fires the
+// next held course.
+var orderItemCascade = map[string]map[string]string{
+	models.OrderStatusServed: {
+		models.OrderItemStatusFired: models.OrderItemStatusServed,
+		models.OrderItemStatusReady: models.OrderItemStatusServed,
+	},
+	models.OrderStatusCancelled: {
+		models.OrderItemStatusHeld:  models.OrderItemStatusVoided,
+		models.OrderItemStatusFired: models.OrderItemStatusWasted,
+		models.OrderItemStatusReady: models.OrderItemStatusWasted,
+	},
+}
+
+// TransitionOrder returns a handler moving an order to status, if the order's
+// current status allows it, and recording who made the change in its history.
+func TransitionOrder(status string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		orderId := c.Param("order_id")
+
+		var change StatusChange
+		if c.Request.ContentLength != 0 {
+			if err := c.BindJSON(&change); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		var order models.Order
+		if err := orderCollection.FindOne(ctx, bson.M{"order_id": orderId}).Decode(&order); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order was not found"})
+			return
+		}
+		order, err := changeOrderStatus(ctx, c, order, status, change.Reason)
+		if err != nil {
+			respondStatusError(c, err)
+			return
+		}
+
+		if status == models.OrderStatusSent {
+			if course, ok := nextHeldCourse(ctx, orderId); ok {
+				if _, err := fireCourse(ctx, c, orderId, course); err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Order item status update failed"})
+					return
+				}
+			}
+			publishTickets(ctx, orderId)
+		}
+		if cascade, ok := orderItemCascade[status]; ok {
+			if err := cascadeItemStatus(ctx, c, orderId, cascade, change.Reason); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Order item status update failed"})
+				return
+			}
+			publishTickets(ctx, orderId)
+		}
+
+		c.JSON(http.StatusOK, order)
+	}
+}
+
+// FireCourse sends the held items of one course of an order to the kitchen,
+// moving the order back to SENT_TO_KITCHEN if it was open or served.
+func FireCourse() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		orderId := c.Param("order_id")
+
+		course, err := strconv.Atoi(c.Param("course"))
+		if err != nil || course < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "course must be a number from 1"})
+			return
+		}
+
+		var order models.Order
+		if err := orderCollection.FindOne(ctx, bson.M{"order_id": orderId}).Decode(&order); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order was not found"})
+			return
+		}
+		if err := checkOrderAcceptsItems(ctx, orderId); err != nil {
+			respondStatusError(c, err)
+			return
+		}
+		if orderStatus(order) != models.OrderStatusSent {
+			if order, err = changeOrderStatus(ctx, c, order, models.OrderStatusSent, ""); err != nil {
+				respondStatusError(c, err)
+				return
+			}
+		}
+
+		fired, err := fireCourse(ctx, c, orderId, course)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Order item status update failed"})
+			return
+		}
+		publishTickets(ctx, orderId)
+
+		c.JSON(http.StatusOK, CourseFiring{Order: order, Course: course, Fired: fired})
+	}
+}
+
+// moves an order to status if its current status allows it, recording the change in its history.
+func changeOrderStatus(ctx context.Context, c *gin.Context, order models.Order, status string, reason string) (models.Order, error) {
+	from := orderStatus(order)
+	if !models.CanTransition(models.OrderTransitions, from, status) {
+		return order, &conflictError{"an order cannot go from " + from + " to " + status}
+	}
+
+	event := newOrderEvent(c, models.OrderEventStatus, from, status, reason)
+	filter := bson.M{"order_id": order.Order_id, "status": statusFilter(order.Status)}
+	update := bson.M{
+		"$set":  bson.M{"status": status, "updated_at": event.At},
+		"$push": bson.M{"history": event},
+	}
+	after := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if err := orderCollection.FindOneAndUpdate(ctx, filter, update, after).Decode(&order); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return order, &conflictError{"the order was changed by someone else, try again"}
+		}
+		return order, err
+	}
+	return order, nil
+}
+
+// fires the held items of a course. Items without a course belong to the first one.
+func fireCourse(ctx context.Context, c *gin.Context, orderId string, course int) (int64, error) {
+	filter := bson.M{"order_id": orderId, "course": course}
+	if course == 1 {
+		filter["course"] = bson.M{"$in": bson.A{1, nil}}
+	}
+	return updateItemStatuses(ctx, c, filter, models.OrderItemStatusHeld, models.OrderItemStatusFired, "course "+strconv.Itoa(course))
+}
+
+// returns the lowest course of the order that still has held items.
+func nextHeldCourse(ctx context.Context, orderId string) (int, bool) {
+	var orderItems []models.OrderItem
+	filter := bson.M{"order_id": orderId, "status": itemStatusFilter(models.OrderItemStatusHeld)}
+	cursor, err := orderItemCollection.Find(ctx, filter)
+	if err != nil || cursor.All(ctx, &orderItems) != nil || len(orderItems) == 0 {
+		return 0, false
+	}
+	next := 0
+	for _, orderItem := range orderItems {
+		course := itemCourse(orderItem)
+		if next == 0 || course < next {
+			next = course
+		}
+	}
+	return next, true
+}
+
+// TransitionOrderItem returns a handler moving an order item to status. The items
+// of a bundle follow their bundle line. Items of closed orders cannot change.
+func TransitionOrderItem(status string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var change StatusChange
+		if c.Request.ContentLength != 0 {
+			if err := c.BindJSON(&change); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		var orderItem models.OrderItem
+		if err := orderItemCollection.FindOne(ctx, bson.M{"order_item_id": c.Param("orderItem_id")}).Decode(&orderItem); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order item was not found"})
+			return
+		}
+
+		orderItem, err := changeItemStatus(ctx, c, orderItem, status, change.Reason)
+		if err != nil {
+			respondStatusError(c, err)
+			return
+		}
+		publishTickets(ctx, orderItem.Order_id)
+
+		c.JSON(http.StatusOK, orderItem)
+	}
+}
+
+// conflictError is a change refused because of the current state of a record.
+type conflictError struct {
+	message string
+}
+
+func (e *conflictError) Error() string {
+	return e.message
+}
+
+// answers 409 for refused changes and 500 for anything else.
+func respondStatusError(c *gin.Context, err error) {
+	var conflict *conflictError
+	if errors.As(err, &conflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": conflict.message})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Order item status update failed"})
+}
+
+// moves an order item to status if its order still accepts changes and its
+// current status allows it, taking the items of a bundle along.
+func changeItemStatus(ctx context.Context, c *gin.Context, orderItem models.OrderItem, status string, reason string) (models.OrderItem, error) {
+	if err := checkOrderAcceptsItems(ctx, orderItem.Order_id); err != nil {
+		return orderItem, err
+	}
+
+	from := itemStatus(orderItem)
+	if !models.CanTransition(models.OrderItemTransitions, from, status) {
+		return orderItem, &conflictError{"an order item cannot go from " + from + " to " + status}
+	}
+
+	event := newOrderEvent(c, models.OrderEventStatus, from, status, reason)
+	filter := bson.M{"order_item_id": orderItem.Order_item_id, "status": itemStatusFilter(orderItem.Status)}
+	update := bson.M{
+		"$set":  itemStatusSet(status, event.At),
+		"$push": bson.M{"history": event},
+	}
+	after := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if err := orderItemCollection.FindOneAndUpdate(ctx, filter, update, after).Decode(&orderItem); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return orderItem, &conflictError{"the order item was changed by someone else, try again"}
+		}
+		return orderItem, err
+	}
+	if status == models.OrderItemStatusReady {
+		if err := recordPrepTimes(ctx, bson.M{"order_item_id": orderItem.Order_item_id}); err != nil {
+			return orderItem, err
+		}
+	}
+
+	if orderItem.Item_type == models.OrderItemTypeBundle {
+		components := bson.M{"parent_order_item_id": orderItem.Order_item_id}
+		if _, err := updateItemStatuses(ctx, c, components, from, status, reason); err != nil {
+			return orderItem, err
+		}
+	}
+	return orderItem, nil
+}
+
+// moves every item of the order whose status is a key of cascade to the status it maps to.
+// Voided items were never made, so their ingredients go back into stock.
+func cascadeItemStatus(ctx context.Context, c *gin.Context, orderId string, cascade map[string]string, reason string) error {
+	for from, status := range cascade {
+		filter := bson.M{"order_id": orderId}
+		if status == models.OrderItemStatusVoided {
+			ids, err := orderItemCollection.Distinct(ctx, "order_item_id", bson.M{"order_id": orderId, "status": itemStatusFilter(from)})
+			if err != nil {
+				return err
+			}
+			filter["order_item_id"] = bson.M{"$in": ids}
+		}
+		if _, err := updateItemStatuses(ctx, c, filter, from, status, reason); err != nil {
+			return err
+		}
+		if status == models.OrderItemStatusVoided {
+			var voided []models.OrderItem
+			cursor, err := orderItemCollection.Find(ctx, bson.M{"order_item_id": filter["order_item_id"], "status": models.OrderItemStatusVoided})
+			if err != nil {
+				return err
+			}
+			if err = cursor.All(ctx, &voided); err != nil {
+				return err
+			}
+			returnStock(ctx, voided)
+		}
+	}
+	return nil
+}
+
+// moves the items matching filter from one status to another, recording the
+// change on each, and returns how many moved.
+func updateItemStatuses(ctx context.Context, c *gin.Context, filter bson.M, from string, status string, reason string) (int64, error) {
+	event := newOrderEvent(c, models.OrderEventStatus, from, status, reason)
+	filter["status"] = itemStatusFilter(from)
+	update := bson.M{
+		"$set":  itemStatusSet(status, event.At),
+		"$push": bson.M{"history": event},
+	}
+	result, err := orderItemCollection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	if status == models.OrderItemStatusReady {
+		ready := bson.M{"ready_at": event.At}
+		for key, value := range filter {
+			if key != "status" {
+				ready[key] = value
+			}
+		}
+		if err := recordPrepTimes(ctx, ready); err != nil {
+			return 0, err
+		}
+	}
+	return result.ModifiedCount, nil
+}
+
+// the fields set when an item moves to status: the status itself and the time
+// it was fired, got ready or was served. A recalled item is no longer ready.
+func itemStatusSet(status string, at time.Time) bson.M {
+	set := bson.M{"status": status, "updated_at": at}
+	switch status {
+	case models.OrderItemStatusFired:
+		set["fired_at"] = at
+		set["ready_at"] = nil
+	case models.OrderItemStatusReady:
+		set["ready_at"] = at
+	case models.OrderItemStatusServed:
+		set["served_at"] = at
+	}
+	return set
+}
+
+// checks items may still be added to or changed on the order.
+func checkOrderAcceptsItems(ctx context.Context, orderId string) error {
+	var order models.Order
+	if err := orderCollection.FindOne(ctx, bson.M{"order_id": orderId}).Decode(&order); err != nil {
+		return err
+	}
+	if status := orderStatus(order); !models.AcceptsItems(status) {
+		return &conflictError{"the order is " + strings.ToLower(strings.ReplaceAll(status, "_", " ")) + ", its items cannot change"}
+	}
+	return nil
+}
+
+// builds a history event for the signed-in user of c (nil for system changes).
+func newOrderEvent(c *gin.Context, action string, from string, to string, reason string) models.OrderEvent {
+	event := models.OrderEvent{Action: action, From: from, To: to, Reason: reason}
+	if c != nil {
+		event.By = c.GetString("uid")
+		event.By_name = strings.TrimSpace(c.GetString("first_name") + " " + c.GetString("last_name"))
+	}
+	event.At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	return event
+}
+
+// orders created before statuses existed count as open.
+func orderStatus(order models.Order) string {
+	if order.Status == "" {
+		return models.OrderStatusOpen
+	}
+	return order.Status
+}
+
+// orders created before types existed were eaten in.
+func orderType(order models.Order) string {
+	if order.Order_type == "" {
+		return models.OrderTypeDineIn
+	}
+	return order.Order_type
+}
+
+// order items created before statuses existed count as held.
+func itemStatus(orderItem models.OrderItem) string {
+	if orderItem.Status == "" {
+		return models.OrderItemStatusHeld
+	}
+	return orderItem.Status
+}
+
+// order items without a course belong to the first one.
+func itemCourse(orderItem models.OrderItem) int {
+	if orderItem.Course == nil {
+		return 1
+	}
+	return *orderItem.Course
+}
+
+// matches the stored status of an order, including orders without one.
+func statusFilter(status string) interface{} {
+	if status == "" || status == models.OrderStatusOpen {
+		return bson.M{"$in": bson.A{models.OrderStatusOpen, nil}}
+	}
+	return status
+}
+
+// matches the stored status of an order item, including items without one.
+func itemStatusFilter(status string) interface{} {
+	if status == "" || status == models.OrderItemStatusHeld {
+		return bson.M{"$in": bson.A{models.OrderItemStatusHeld, nil}}
+	}
+	return status
+}
@@ -0,0 +1,343 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"golang-Restaurant-Management-backend/models"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TableMove is the body of a request moving an order to another table.
+type TableMove struct {
+	Table_id string `json:"table_id" validate:"required"`
+	Reason   string `json:"reason"`
+}
+
+// OrderMerge is the body of a request merging another order into this one.
+type OrderMerge struct {
+	Order_id string `json:"order_id" validate:"required"`
+	Reason   string `json:"reason"`
+}
+
+// ItemsMove is the body of a request moving items of this order to another one.
+type ItemsMove struct {
+	Order_item_ids []string `json:"order_item_ids" validate:"required,min=1"`
+	To_order_id    string   `json:"to_order_id" validate:"required"`
+	Reason         string   `json:"reason"`
+}
+
+// OrderTransfer is the result of a merge or an item move: the order the items
+// left and the order they joined, and how many items moved.
+type OrderTransfer struct {
+	From  models.Order `json:"from"`
+	To    models.Order `json:"to"`
+	Moved int64        `json:"moved"`
+}
+
+// MoveOrderTable moves a dine-in order to another table. A table eats from one
+// order, so moving to a table that has an open order is refused: merge instead.
+func MoveOrderTable() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var move TableMove
+		if err := c.BindJSON(&move); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if validationErr := validate.Struct(move); validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		var order models.Order
+		if err := orderCollection.FindOne(ctx, bson.M{"order_id": c.Param("order_id")}).Decode(&order); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order was not found"})
+			return
+		}
+		var table models.Table
+		if err := tableCollection.FindOne(ctx, bson.M{"table_id": move.Table_id}).Decode(&table); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Table was not found"})
+			return
+		}
+
+		if err := checkOrderAcceptsItems(ctx, order.Order_id); err != nil {
+			respondStatusError(c, err)
+			return
+		}
+		if orderType(order) != models.OrderTypeDineIn {
+			c.JSON(http.StatusConflict, gin.H{"error": "only dine-in orders sit at a table"})
+			return
+		}
+		if order.Table_id != nil && *order.Table_id == move.Table_id {
+			c.JSON(http.StatusConflict, gin.H{"error": "the order is already at this table"})
+			return
+		}
+		if occupying, ok := openTableOrder(ctx, models.Order{Order_type: models.OrderTypeDineIn, Table_id: &move.Table_id}); ok {
+			c.JSON(http.StatusConflict, gin.H{"error": "the table already has open order " + occupying.Order_id + ", merge the orders instead"})
+			return
+		}
+
+		from := ""
+		if order.Table_id != nil {
+			from = *order.Table_id
+		}
+		event := newOrderEvent(c, models.OrderEventMoveTable, from, move.Table_id, move.Reason)
+		update := bson.M{
+			"$set":  bson.M{"table_id": move.Table_id, "updated_at": event.At},
+			"$push": bson.M{"history": event},
+		}
+		after := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		if err := orderCollection.FindOneAndUpdate(ctx, bson.M{"order_id": order.Order_id}, update, after).Decode(&order); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Order update failed"})
+			return
+		}
+		publishTickets(ctx, order.Order_id)
+
+		c.JSON(http.StatusOK, order)
+	}
+}
+
+// MergeOrders moves every item and note of another order into this one, as when
+// two tables join, and marks the other order MERGED. Orders that were already
+// invoiced cannot be merged, either way.
+func MergeOrders() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var merge OrderMerge
+		if err := c.BindJSON(&merge); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if validationErr := validate.Struct(merge); validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		source, target, err := transferOrders(ctx, merge.Order_id, c.Param("order_id"))
+		if err != nil {
+			respondTransferError(c, err)
+			return
+		}
+		if err := checkNotInvoiced(ctx, source.Order_id, target.Order_id); err != nil {
+			respondStatusError(c, err)
+			return
+		}
+
+		// Claim the source first: once it is merged nothing more can change on it,
+		// and a source paid or cancelled meanwhile is refused before anything moves.
+		if source, err = changeOrderStatus(ctx, c, source, models.OrderStatusMerged, merge.Reason); err != nil {
+			respondStatusError(c, err)
+			return
+		}
+		event := newOrderEvent(c, models.OrderEventMerge, source.Order_id, target.Order_id, merge.Reason)
+		if source, err = recordOrderEvent(ctx, source.Order_id, event, bson.M{"merged_into": target.Order_id}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Order update failed"})
+			return
+		}
+
+		moved, err := moveOrderItems(ctx, bson.M{"order_id": source.Order_id}, event)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Order items were not moved"})
+			return
+		}
+		noteFilter := bson.M{"entity_type": models.NoteOnOrder, "entity_id": source.Order_id}
+		if _, err := noteCollection.UpdateMany(ctx, noteFilter, bson.M{"$set": bson.M{"entity_id": target.Order_id}}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Order notes were not moved"})
+			return
+		}
+		if target, err = recordOrderEvent(ctx, target.Order_id, event, nil); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Order update failed"})
+			return
+		}
+		for _, orderId := range []string{source.Order_id, target.Order_id} {
+			if err := scheduleRelease(ctx, orderId); err != nil {
+				log.Println("release time of order", orderId, "was not set:", err)
+			}
+			publishTickets(ctx, orderId)
+		}
+
+		c.JSON(http.StatusOK, OrderTransfer{From: source, To: target, Moved: moved})
+	}
+}
+
+// MoveItems moves selected items of this order to another open order, as when
+// one guest of a table moves to the bar. The items of a bundle move with it.
+// Items cannot move in or out of an invoiced order.
+func MoveItems() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var move ItemsMove
+		if err := c.BindJSON(&move); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if validationErr := validate.Struct(move); validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		source, target, err := transferOrders(ctx, c.Param("order_id"), move.To_order_id)
+		if err != nil {
+			respondTransferError(c, err)
+			return
+		}
+		if err := checkNotInvoiced(ctx, source.Order_id, target.Order_id); err != nil {
+			respondStatusError(c, err)
+			return
+		}
+
+		var orderItems []models.OrderItem
+		cursor, err := orderItemCollection.Find(ctx, bson.M{"order_id": source.Order_id, "order_item_id": bson.M{"$in": move.Order_item_ids}})
+		if err == nil {
+			err = cursor.All(ctx, &orderItems)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while fetching the order items"})
+			return
+		}
+		if len(orderItems) != len(move.Order_item_ids) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Some of the order items are not on order " + source.Order_id})
+			return
+		}
+		for _, orderItem := range orderItems {
+			if orderItem.Item_type == models.OrderItemTypeComponent {
+				c.JSON(http.StatusConflict, gin.H{"error": "order item " + orderItem.Order_item_id + " is part of a bundle, move the bundle instead"})
+				return
+			}
+		}
+
+		event := newOrderEvent(c, models.OrderEventMoveItems, source.Order_id, target.Order_id, move.Reason)
+		filter := bson.M{"order_id": source.Order_id, "$or": bson.A{
+			bson.M{"order_item_id": bson.M{"$in": move.Order_item_ids}},
+			bson.M{"parent_order_item_id": bson.M{"$in": move.Order_item_ids}},
+		}}
+		moved, err := moveOrderItems(ctx, filter, event)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Order items were not moved"})
+			return
+		}
+
+		event.Reason = joinReason(strconv.FormatInt(moved, 10)+" items", move.Reason)
+		if source, err = recordOrderEvent(ctx, source.Order_id, event, nil); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Order update failed"})
+			return
+		}
+		if target, err = recordOrderEvent(ctx, target.Order_id, event, nil); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Order update failed"})
+			return
+		}
+		for _, orderId := range []string{source.Order_id, target.Order_id} {
+			if err := scheduleRelease(ctx, orderId); err != nil {
+				log.Println("release time of order", orderId, "was not set:", err)
+			}
+			publishTickets(ctx, orderId)
+		}
+
+		c.JSON(http.StatusOK, OrderTransfer{From: source, To: target, Moved: moved})
+	}
+}
+
+// notFoundError is a transfer refused because one of its orders does not exist.
+type notFoundError struct {
+	message string
+}
+
+func (e *notFoundError) Error() string {
+	return e.message
+}
+
+// answers 404 for missing orders, otherwise like respondStatusError.
+func respondTransferError(c *gin.Context, err error) {
+	var missing *notFoundError
+	if errors.As(err, &missing) {
+		c.JSON(http.StatusNotFound, gin.H{"error": missing.message})
+		return
+	}
+	respondStatusError(c, err)
+}
+
+// loads the two orders of a transfer, checking they differ and both still accept items.
+func transferOrders(ctx context.Context, fromId string, toId string) (models.Order, models.Order, error) {
+	var from, to models.Order
+	if fromId == toId {
+		return from, to, &conflictError{"items can only move to another order"}
+	}
+	for _, order := range []struct {
+		id    string
+		found *models.Order
+	}{{fromId, &from}, {toId, &to}} {
+		if err := orderCollection.FindOne(ctx, bson.M{"order_id": order.id}).Decode(order.found); err != nil {
+			return from, to, &notFoundError{"Order " + order.id + " was not found"}
+		}
+		if err := checkOrderAcceptsItems(ctx, order.id); err != nil {
+			return from, to, err
+		}
+	}
+	return from, to, nil
+}
+
+// refuses a transfer touching an order that was already invoiced, as the
+// invoice would no longer match its items.
+func checkNotInvoiced(ctx context.Context, orderIds ...string) error {
+	for _, orderId := range orderIds {
+		invoices, err := invoiceCollection.CountDocuments(ctx, bson.M{"order_id": orderId})
+		if err != nil {
+			return err
+		}
+		if invoices > 0 {
+			return &conflictError{"order " + orderId + " was already invoiced"}
+		}
+	}
+	return nil
+}
+
+// moves the items matching filter to the order event.To, recording the move on each item.
+func moveOrderItems(ctx context.Context, filter bson.M, event models.OrderEvent) (int64, error) {
+	update := bson.M{
+		"$set":  bson.M{"order_id": event.To, "updated_at": event.At},
+		"$push": bson.M{"history": event},
+	}
+	result, err := orderItemCollection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// adds event to the history of an order, setting any extra fields, and returns the updated order.
+func recordOrderEvent(ctx context.Context, orderId string, event models.OrderEvent, set bson.M) (models.Order, error) {
+	var order models.Order
+	if set == nil {
+		set = bson.M{}
+	}
+	set["updated_at"] = event.At
+	update := bson.M{"$set": set, "$push": bson.M{"history": event}}
+	after := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := orderCollection.FindOneAndUpdate(ctx, bson.M{"order_id": orderId}, update, after).Decode(&order)
+	if err == mongo.ErrNoDocuments {
+		return order, &notFoundError{"Order " + orderId + " was not found"}
+	}
+	return order, err
+}
+
+// joins a generated reason with the one given by staff, if any.
+func joinReason(reason string, given string) string {
+	if given == "" {
+		return reason
+	}
+	return reason + ": " + given
+}
@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"context"
+	"golang-Restaurant-Management-backend/database"
+	"golang-Restaurant-Management-backend/models"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var priceChangeCollection *mongo.Collection = database.OpenCollection(database.Client, "price_change")
+
+// GetPriceHistory lists the price changes of a food, newest first.
+// With ?at=<RFC3339 time> it returns only the price that was in effect at that moment.
+func GetPriceHistory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		foodId := c.Param("food_id")
+		filter := bson.M{"food_id": foodId}
+
+		if at := c.Query("at"); at != "" {
+			moment, err := time.Parse(time.RFC3339, at)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "at must be an RFC3339 time"})
+				return
+			}
+			filter["changed_at"] = bson.M{"$lte": moment}
+
+			var change models.PriceChange
+			opts := options.FindOne().SetSort(bson.D{{"changed_at", -1}})
+			if err := priceChangeCollection.FindOne(ctx, filter, opts).Decode(&change); err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "No price was recorded for this food at that time"})
+				return
+			}
+			c.JSON(http.StatusOK, change)
+			return
+		}
+
+		opts := options.Find().SetSort(bson.D{{"changed_at", -1}})
+		result, err := priceChangeCollection.Find(ctx, filter, opts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while listing the price history"})
+			return
+		}
+
+		var allChanges []bson.M
+		if err = result.All(ctx, &allChanges); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, allChanges)
+	}
+}
+
+// records a price change of a food made by the signed-in user of c (nil for system changes).
+// Nothing is recorded when the price did not actually change.
+func recordPriceChange(ctx context.Context, c *gin.Context, change models.PriceChange) error {
+	if change.Old_price != nil && *change.Old_price == change.New_price {
+		return nil
+	}
+	if c != nil {
+		change.Changed_by = c.GetString("uid")
+		change.Changed_by_name = strings.TrimSpace(c.GetString("first_name") + " " + c.GetString("last_name"))
+	}
+	change.Changed_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	change.ID = primitive.NewObjectID()
+	change.Price_change_id = change.ID.Hex()
+
+	_, err := priceChangeCollection.InsertOne(ctx, change)
+	return err
+}
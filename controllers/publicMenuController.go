@@ -0,0 +1,229 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"golang-Restaurant-Management-backend/models"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// PublicMenu is the guest-facing view of a published menu, without internal
+// fields such as costs, SKUs, translations or timestamps.
+type PublicMenu struct {
+	Menu_id    string            `json:"menu_id"`
+	Name       string            `json:"name"`
+	Category   string            `json:"category"`
+	Version    int               `json:"version"`
+	Categories []*PublicCategory `json:"categories,omitempty"`
+	Foods      []PublicFood      `json:"foods,omitempty"`
+}
+
+type PublicCategory struct {
+	Category_id string            `json:"category_id"`
+	Name        string            `json:"name"`
+	Foods       []PublicFood      `json:"foods"`
+	Children    []*PublicCategory `json:"children"`
+}
+
+type PublicFood struct {
+	Food_id     string            `json:"food_id"`
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Tags        []string          `json:"tags"`
+	Allergens   []string          `json:"allergens"`
+	Price       float64           `json:"price"`
+	Nutrition   *models.Nutrition `json:"nutrition,omitempty"`
+	Food_image  string            `json:"food_image,omitempty"`
+	Images      map[string]string `json:"images,omitempty"`
+}
+
+// how long browsers and CDNs may reuse a public menu before revalidating it.
+const publicMenuCacheControl = "public, max-age=60, stale-while-revalidate=600"
+
+// GetPublicMenus lists the menus guests can order from right now.
+func GetPublicMenus() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var menus []models.Menu
+		cursor, err := menuCollection.Find(ctx, bson.M{"published_version_id": bson.M{"$ne": nil}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while listing the menus"})
+			return
+		}
+		if err = cursor.All(ctx, &menus); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while listing the menus"})
+			return
+		}
+
+		locales := requestLocales(c)
+		publicMenus := []PublicMenu{}
+		for _, menu := range menus {
+			if !menuAvailable(menu, time.Now()) {
+				continue
+			}
+			localizeMenu(&menu, locales)
+			publicMenus = append(publicMenus, PublicMenu{Menu_id: menu.Menu_id, Name: menu.Name, Category: menu.Category, Version: menu.Published_version})
+		}
+		respondCacheable(c, publicMenus)
+	}
+}
+
+// GetPublicMenu returns a published, currently available menu with its categories
+// and foods, as they are in the published version.
+func GetPublicMenu() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tree, err := buildMenuTree(ctx, c.Param("menu_id"), bson.M{"off_menu": bson.M{"$ne": true}})
+		if err == mongo.ErrNoDocuments || (err == nil && (tree.Menu.Published_version_id == nil || !menuAvailable(tree.Menu, time.Now()))) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "menu was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while building the menu"})
+			return
+		}
+		var version models.MenuVersion
+		if err := menuVersionCollection.FindOne(ctx, bson.M{"menu_version_id": tree.Menu.Published_version_id}).Decode(&version); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while building the menu"})
+			return
+		}
+		servePublishedVersion(&tree, version)
+		localizeTree(&tree, requestLocales(c))
+
+		menu := PublicMenu{
+			Menu_id:    tree.Menu.Menu_id,
+			Name:       tree.Menu.Name,
+			Category:   tree.Menu.Category,
+			Version:    tree.Menu.Published_version,
+			Categories: publicCategories(tree.Categories),
+			Foods:      publicFoods(tree.Uncategorized),
+		}
+		respondCacheable(c, menu)
+	}
+}
+
+// reports whether the menu's start/end dates include now. Missing dates leave that side open.
+func menuAvailable(menu models.Menu, now time.Time) bool {
+	if menu.Start_Date != nil && now.Before(*menu.Start_Date) {
+		return false
+	}
+	if menu.End_Date != nil && now.After(*menu.End_Date) {
+		return false
+	}
+	return true
+}
+
+// gives the foods of the tree the content of the published version, leaving
+// out foods added since or dropped from it.
+func servePublishedVersion(tree *MenuTree, version models.MenuVersion) {
+	items := map[string]models.MenuVersionItem{}
+	for _, item := range version.Items {
+		items[item.Food_id] = item
+	}
+	tree.Uncategorized = versionFoods(tree.Uncategorized, items)
+	var serveNodes func(nodes []*CategoryNode)
+	serveNodes = func(nodes []*CategoryNode) {
+		for _, node := range nodes {
+			node.Foods = versionFoods(node.Foods, items)
+			serveNodes(node.Children)
+		}
+	}
+	serveNodes(tree.Categories)
+}
+
+func versionFoods(foods []models.Food, items map[string]models.MenuVersionItem) []models.Food {
+	served := []models.Food{}
+	for _, food := range foods {
+		item, ok := items[food.Food_id]
+		if !ok {
+			continue
+		}
+		// The resized images belong to the live image.
+		if stringValue(item.Food_image) != stringValue(food.Food_image) {
+			food.Images = nil
+		}
+		food.Name = item.Name
+		food.Description = item.Description
+		food.Tags = item.Tags
+		food.Price = item.Price
+		food.Food_image = item.Food_image
+		served = append(served, food)
+	}
+	return served
+}
+
+func publicCategories(nodes []*CategoryNode) []*PublicCategory {
+	categories := []*PublicCategory{}
+	for _, node := range nodes {
+		foods := publicFoods(node.Foods)
+		children := publicCategories(node.Children)
+		// Empty categories would only clutter the guest menu.
+		if len(foods) == 0 && len(children) == 0 {
+			continue
+		}
+		categories = append(categories, &PublicCategory{Category_id: node.Category_id, Name: node.Name, Foods: foods, Children: children})
+	}
+	return categories
+}
+
+func publicFoods(foods []models.Food) []PublicFood {
+	publicFoods := []PublicFood{}
+	for _, food := range foods {
+		if food.Price == nil {
+			continue
+		}
+		publicFood := PublicFood{
+			Food_id:     food.Food_id,
+			Name:        stringValue(food.Name),
+			Description: stringValue(food.Description),
+			Tags:        food.Tags,
+			Allergens:   food.Allergens,
+			Price:       *food.Price,
+			Nutrition:   food.Nutrition,
+			Food_image:  stringValue(food.Food_image),
+			Images:      food.Images,
+		}
+		if publicFood.Tags == nil {
+			publicFood.Tags = []string{}
+		}
+		if publicFood.Allergens == nil {
+			publicFood.Allergens = []string{}
+		}
+		publicFoods = append(publicFoods, publicFood)
+	}
+	return publicFoods
+}
+
+// writes body as JSON with a strong ETag of its content, answering
+// If-None-Match with 304 so QR menus are cheap to refresh.
+func respondCacheable(c *gin.Context, body interface{}) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	sum := sha256.Sum256(data)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", publicMenuCacheControl)
+	for _, match := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+		if match = strings.TrimSpace(match); match == etag || match == "*" {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+}
@@ -0,0 +1,471 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"golang-Restaurant-Management-backend/database"
+	"golang-Restaurant-Management-backend/models"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PurchaseOrderReceipt is the body of a receive request. Lines that are left out
+// are taken as received in full at the ordered cost.
+type PurchaseOrderReceipt struct {
+	Lines []struct {
+		Supplier_item_id string   `json:"supplier_item_id"`
+		Received_packs   *float64 `json:"received_packs"`
+		Pack_cost        *float64 `json:"pack_cost"`
+	} `json:"lines"`
+}
+
+// ReceiveLineError reports a received line whose stock could not be booked.
+type ReceiveLineError struct {
+	Supplier_item_id string `json:"supplier_item_id"`
+	Ingredient_id    string `json:"ingredient_id"`
+	Error            string `json:"error"`
+}
+
+// ReorderSuggestion groups the ingredients below par level by the supplier that sells them cheapest.
+type ReorderSuggestion struct {
+	Supplier_id   string                     `json:"supplier_id"`
+	Supplier_name string                     `json:"supplier_name"`
+	Lines         []models.PurchaseOrderLine `json:"lines"`
+	Total         float64                    `json:"total"`
+}
+
+var purchaseOrderCollection *mongo.Collection = database.OpenCollection(database.Client, "purchase_order")
+
+func GetPurchaseOrders() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		filter := bson.M{}
+		if status := c.Query("status"); status != "" {
+			filter["status"] = status
+		}
+
+		opts := options.Find().SetSort(bson.D{{"created_at", -1}})
+		result, err := purchaseOrderCollection.Find(ctx, filter, opts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while listing purchase orders"})
+			return
+		}
+
+		var allPurchaseOrders []bson.M
+		if err = result.All(ctx, &allPurchaseOrders); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, allPurchaseOrders)
+	}
+}
+
+func GetPurchaseOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		purchaseOrderId := c.Param("purchase_order_id")
+		var purchaseOrder models.PurchaseOrder
+
+		err := purchaseOrderCollection.FindOne(ctx, bson.M{"purchase_order_id": purchaseOrderId}).Decode(&purchaseOrder)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while fetching the purchase order"})
+			return
+		}
+
+		c.JSON(http.StatusOK, purchaseOrder)
+	}
+}
+
+// CreatePurchaseOrder creates a draft purchase order for one supplier.
+func CreatePurchaseOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var purchaseOrder models.PurchaseOrder
+
+		if err := c.BindJSON(&purchaseOrder); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		validationErr := validate.Struct(purchaseOrder)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		count, err := supplierCollection.CountDocuments(ctx, bson.M{"supplier_id": purchaseOrder.Supplier_id})
+		if err != nil || count == 0 {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Supplier was not found"})
+			return
+		}
+
+		lines, total, err := pricePurchaseOrderLines(ctx, *purchaseOrder.Supplier_id, purchaseOrder.Lines)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		purchaseOrder.Lines = lines
+		purchaseOrder.Total = total
+
+		purchaseOrder.Status = models.PurchaseOrderDraft
+		purchaseOrder.Created_by = c.GetString("uid")
+		purchaseOrder.Sent_at = nil
+		purchaseOrder.Received_at = nil
+		purchaseOrder.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		purchaseOrder.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		purchaseOrder.ID = primitive.NewObjectID()
+		purchaseOrder.Purchase_order_id = purchaseOrder.ID.Hex()
+
+		result, insertErr := purchaseOrderCollection.InsertOne(ctx, purchaseOrder)
+		if insertErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Purchase order was not created"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// UpdatePurchaseOrder replaces the lines of a purchase order that is still a draft.
+func UpdatePurchaseOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var update models.PurchaseOrder
+		var purchaseOrder models.PurchaseOrder
+		purchaseOrderId := c.Param("purchase_order_id")
+
+		if err := c.BindJSON(&update); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if validationErr := validate.Var(update.Lines, "required,min=1,dive"); validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		err := purchaseOrderCollection.FindOne(ctx, bson.M{"purchase_order_id": purchaseOrderId}).Decode(&purchaseOrder)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Purchase order was not found"})
+			return
+		}
+
+		lines, total, err := pricePurchaseOrderLines(ctx, *purchaseOrder.Supplier_id, update.Lines)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var updateObj primitive.D
+		updateObj = append(updateObj, bson.E{"lines", lines})
+		updateObj = append(updateObj, bson.E{"total", total})
+		update.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		updateObj = append(updateObj, bson.E{"updated_at", update.Updated_at})
+
+		// Only drafts can be edited.
+		filter := bson.M{"purchase_order_id": purchaseOrderId, "status": models.PurchaseOrderDraft}
+		result, err := purchaseOrderCollection.UpdateOne(ctx, filter, bson.D{{"$set", updateObj}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Purchase order update failed"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Only draft purchase orders can be edited"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// SendPurchaseOrder marks a draft as sent to the supplier.
+func SendPurchaseOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		purchaseOrderId := c.Param("purchase_order_id")
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		filter := bson.M{"purchase_order_id": purchaseOrderId, "status": models.PurchaseOrderDraft}
+		update := bson.M{"$set": bson.M{"status": models.PurchaseOrderSent, "sent_at": now, "updated_at": now}}
+
+		result, err := purchaseOrderCollection.UpdateOne(ctx, filter, update)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Purchase order was not sent"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Only draft purchase orders can be sent"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// ReceivePurchaseOrder books a sent purchase order into stock. Each received
+// line increments the ingredient's stock and records the cost paid. Lines whose
+// stock could not be booked are answered with a 500 and booked by receiving the
+// order again with ?retry=true.
+func ReceivePurchaseOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var receipt PurchaseOrderReceipt
+		var purchaseOrder models.PurchaseOrder
+		purchaseOrderId := c.Param("purchase_order_id")
+
+		// The body is optional: without it everything is received as ordered.
+		if c.Request.ContentLength != 0 {
+			if err := c.BindJSON(&receipt); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		err := purchaseOrderCollection.FindOne(ctx, bson.M{"purchase_order_id": purchaseOrderId}).Decode(&purchaseOrder)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Purchase order was not found"})
+			return
+		}
+
+		if c.Query("retry") == "true" {
+			if purchaseOrder.Status != models.PurchaseOrderReceived {
+				c.JSON(http.StatusConflict, gin.H{"error": "Only received purchase orders can be retried"})
+				return
+			}
+			if failed := stockReceivedLines(ctx, purchaseOrder, c.GetString("uid")); len(failed) > 0 {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Stock of some lines was not booked", "failed_lines": failed})
+				return
+			}
+			c.JSON(http.StatusOK, purchaseOrder)
+			return
+		}
+
+		for i := range purchaseOrder.Lines {
+			line := &purchaseOrder.Lines[i]
+			received := line.Packs
+			line.Received_packs = &received
+			line.Stocked = false
+			for _, receivedLine := range receipt.Lines {
+				if receivedLine.Supplier_item_id != line.Supplier_item_id {
+					continue
+				}
+				if receivedLine.Received_packs != nil {
+					if *receivedLine.Received_packs < 0 {
+						c.JSON(http.StatusBadRequest, gin.H{"error": "received_packs must not be negative"})
+						return
+					}
+					line.Received_packs = receivedLine.Received_packs
+				}
+				if receivedLine.Pack_cost != nil {
+					line.Pack_cost = toFixed(*receivedLine.Pack_cost, 2)
+				}
+			}
+		}
+
+		// Flip the status first so a purchase order can never be received twice.
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		filter := bson.M{"purchase_order_id": purchaseOrderId, "status": models.PurchaseOrderSent}
+		update := bson.M{"$set": bson.M{
+			"status":      models.PurchaseOrderReceived,
+			"lines":       purchaseOrder.Lines,
+			"total":       receivedTotal(purchaseOrder.Lines),
+			"received_at": now,
+			"updated_at":  now,
+		}}
+		result, err := purchaseOrderCollection.UpdateOne(ctx, filter, update)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Purchase order was not received"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Only sent purchase orders can be received"})
+			return
+		}
+
+		if failed := stockReceivedLines(ctx, purchaseOrder, c.GetString("uid")); len(failed) > 0 {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Stock of some lines was not booked, receive again with ?retry=true", "failed_lines": failed})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// books the received lines of a purchase order that are not stocked yet and marks
+// them stocked. Lines are booked in order, so a line whose restock was already
+// recorded is only marked and a retry never books it twice. Returns the lines that failed.
+func stockReceivedLines(ctx context.Context, purchaseOrder models.PurchaseOrder, createdBy string) []ReceiveLineError {
+	failed := []ReceiveLineError{}
+	reason := "purchase order " + purchaseOrder.Purchase_order_id
+	// earlier received lines per ingredient, an ingredient can come in more than one pack size
+	earlier := map[string]int64{}
+	blocked := map[string]bool{}
+	for i, line := range purchaseOrder.Lines {
+		if line.Received_packs == nil || *line.Received_packs == 0 {
+			continue
+		}
+		ordinal := earlier[line.Ingredient_id]
+		earlier[line.Ingredient_id]++
+		if line.Stocked {
+			continue
+		}
+		if blocked[line.Ingredient_id] {
+			failed = append(failed, ReceiveLineError{Supplier_item_id: line.Supplier_item_id, Ingredient_id: line.Ingredient_id, Error: "an earlier line of the ingredient was not booked"})
+			continue
+		}
+
+		recorded, err := stockAdjustmentCollection.CountDocuments(ctx, bson.M{"ingredient_id": line.Ingredient_id, "type": models.StockRestock, "reason": reason})
+		if err == nil && recorded <= ordinal {
+			restock := models.StockRestock
+			quantity := *line.Received_packs * line.Pack_size
+			unitCost := line.Pack_cost / line.Pack_size
+			_, err = applyStockAdjustment(ctx, models.StockAdjustment{
+				Ingredient_id: line.Ingredient_id,
+				Type:          &restock,
+				Quantity:      &quantity,
+				Reason:        &reason,
+				Unit_cost:     &unitCost,
+				Created_by:    createdBy,
+			})
+		}
+		if err == nil {
+			_, err = purchaseOrderCollection.UpdateOne(ctx,
+				bson.M{"purchase_order_id": purchaseOrder.Purchase_order_id},
+				bson.M{"$set": bson.M{fmt.Sprintf("lines.%d.stocked", i): true}})
+		}
+		if err != nil {
+			log.Println("receiving purchase order", purchaseOrder.Purchase_order_id, err)
+			blocked[line.Ingredient_id] = true
+			failed = append(failed, ReceiveLineError{Supplier_item_id: line.Supplier_item_id, Ingredient_id: line.Ingredient_id, Error: err.Error()})
+		}
+	}
+	return failed
+}
+
+// GetSuggestedReorder lists what to order to bring every ingredient back to its
+// par level, buying each ingredient from the supplier with the lowest unit cost.
+func GetSuggestedReorder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		filter := bson.M{"par_level": bson.M{"$ne": nil}, "$expr": bson.M{"$lt": bson.A{"$stock", "$par_level"}}}
+		cursor, err := ingredientCollection.Find(ctx, filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while listing ingredients below par"})
+			return
+		}
+		var ingredients []models.Ingredient
+		if err = cursor.All(ctx, &ingredients); err != nil {
+			log.Fatal(err)
+		}
+
+		suggestions := map[string]*ReorderSuggestion{}
+		order := []string{}
+		for _, ingredient := range ingredients {
+			var items []models.SupplierItem
+			cursor, err := supplierItemCollection.Find(ctx, bson.M{"ingredient_id": ingredient.Ingredient_id})
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while listing supplier items"})
+				return
+			}
+			if err = cursor.All(ctx, &items); err != nil {
+				log.Fatal(err)
+			}
+
+			best, ok := cheapestSupplierItem(items)
+			if !ok {
+				continue
+			}
+
+			stock := 0.0
+			if ingredient.Stock != nil {
+				stock = *ingredient.Stock
+			}
+			packs := math.Ceil((*ingredient.Par_level - stock) / *best.Pack_size)
+
+			suggestion, ok := suggestions[best.Supplier_id]
+			if !ok {
+				suggestion = &ReorderSuggestion{Supplier_id: best.Supplier_id}
+				suggestions[best.Supplier_id] = suggestion
+				order = append(order, best.Supplier_id)
+			}
+			suggestion.Lines = append(suggestion.Lines, models.PurchaseOrderLine{
+				Supplier_item_id: best.Supplier_item_id,
+				Ingredient_id:    ingredient.Ingredient_id,
+				Packs:            packs,
+				Pack_size:        *best.Pack_size,
+				Pack_cost:        *best.Pack_cost,
+			})
+			suggestion.Total = toFixed(suggestion.Total+packs*(*best.Pack_cost), 2)
+		}
+
+		result := []ReorderSuggestion{}
+		for _, supplierId := range order {
+			var supplier models.Supplier
+			suggestion := suggestions[supplierId]
+			if err := supplierCollection.FindOne(ctx, bson.M{"supplier_id": supplierId}).Decode(&supplier); err == nil {
+				suggestion.Supplier_name = *supplier.Name
+			}
+			result = append(result, *suggestion)
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// copies pack size and cost from the supplier catalog into the lines and totals them.
+func pricePurchaseOrderLines(ctx context.Context, supplierId string, lines []models.PurchaseOrderLine) ([]models.PurchaseOrderLine, float64, error) {
+	total := 0.0
+	for i := range lines {
+		var item models.SupplierItem
+		err := supplierItemCollection.FindOne(ctx, bson.M{"supplier_item_id": lines[i].Supplier_item_id, "supplier_id": supplierId}).Decode(&item)
+		if err != nil {
+			return nil, 0, fmt.Errorf("supplier item %s was not found for this supplier", lines[i].Supplier_item_id)
+		}
+		lines[i].Ingredient_id = *item.Ingredient_id
+		lines[i].Pack_size = *item.Pack_size
+		lines[i].Pack_cost = *item.Pack_cost
+		lines[i].Received_packs = nil
+		total += lines[i].Packs * lines[i].Pack_cost
+	}
+	return lines, toFixed(total, 2), nil
+}
+
+func receivedTotal(lines []models.PurchaseOrderLine) float64 {
+	total := 0.0
+	for _, line := range lines {
+		if line.Received_packs != nil {
+			total += *line.Received_packs * line.Pack_cost
+		}
+	}
+	return toFixed(total, 2)
+}
+
+// picks the catalog item with the lowest cost per ingredient unit.
+func cheapestSupplierItem(items []models.SupplierItem) (models.SupplierItem, bool) {
+	var best models.SupplierItem
+	found := false
+	for _, item := range items {
+		if item.Pack_size == nil || item.Pack_cost == nil {
+			continue
+		}
+		if !found || *item.Pack_cost / *item.Pack_size < *best.Pack_cost / *best.Pack_size {
+			best, found = item, true
+		}
+	}
+	return best, found
+}
@@ -0,0 +1,190 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"golang-Restaurant-Management-backend/database"
+	"golang-Restaurant-Management-backend/models"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var recipeCollection *mongo.Collection = database.OpenCollection(database.Client, "recipe")
+
+func GetRecipes() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		filter := bson.M{}
+		if foodId := c.Query("food_id"); foodId != "" {
+			filter["food_id"] = foodId
+		}
+
+		result, err := recipeCollection.Find(ctx, filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while listing recipes"})
+			return
+		}
+
+		var allRecipes []bson.M
+		if err = result.All(ctx, &allRecipes); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, allRecipes)
+	}
+}
+
+func GetRecipe() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		recipeId := c.Param("recipe_id")
+		var recipe models.Recipe
+
+		err := recipeCollection.FindOne(ctx, bson.M{"recipe_id": recipeId}).Decode(&recipe)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while fetching the recipe"})
+			return
+		}
+
+		c.JSON(http.StatusOK, recipe)
+	}
+}
+
+func CreateRecipe() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var recipe models.Recipe
+
+		if err := c.BindJSON(&recipe); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		validationErr := validate.Struct(recipe)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		// Check the food exists and has no recipe for this modifier yet.
+		count, err := foodCollection.CountDocuments(ctx, bson.M{"food_id": recipe.Food_id})
+		if err != nil || count == 0 {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Food was not found"})
+			return
+		}
+		count, err = recipeCollection.CountDocuments(ctx, bson.M{"food_id": recipe.Food_id, "modifier": recipe.Modifier})
+		if err != nil || count > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A recipe for this food and modifier already exists"})
+			return
+		}
+		if err := checkRecipeLines(ctx, recipe.Lines); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		recipe.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		recipe.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		recipe.ID = primitive.NewObjectID()
+		recipe.Recipe_id = recipe.ID.Hex()
+
+		result, insertErr := recipeCollection.InsertOne(ctx, recipe)
+		if insertErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Recipe was not created"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// UpdateRecipe replaces the ingredient lines of a recipe.
+func UpdateRecipe() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var recipe models.Recipe
+		recipeId := c.Param("recipe_id")
+
+		if err := c.BindJSON(&recipe); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if validationErr := validate.Var(recipe.Lines, "required,min=1,dive"); validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+		if err := checkRecipeLines(ctx, recipe.Lines); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var updateObj primitive.D
+		updateObj = append(updateObj, bson.E{"lines", recipe.Lines})
+		recipe.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		updateObj = append(updateObj, bson.E{"updated_at", recipe.Updated_at})
+
+		result, err := recipeCollection.UpdateOne(
+			ctx,
+			bson.M{"recipe_id": recipeId},
+			bson.D{
+				{"$set", updateObj},
+			},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Recipe update failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// checks every recipe line points at an existing ingredient.
+func checkRecipeLines(ctx context.Context, lines []models.RecipeLine) error {
+	for _, line := range lines {
+		count, err := ingredientCollection.CountDocuments(ctx, bson.M{"ingredient_id": line.Ingredient_id})
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return fmt.Errorf("ingredient %s was not found", line.Ingredient_id)
+		}
+	}
+	return nil
+}
+
+// returns the quantity of each ingredient used by one portion of the food,
+// adding the recipes of the given modifiers to the base recipe.
+func recipeUsage(ctx context.Context, foodId string, modifiers []string) (map[string]float64, error) {
+	recipeFilter := bson.A{bson.M{"modifier": nil}}
+	if len(modifiers) > 0 {
+		recipeFilter = append(recipeFilter, bson.M{"modifier": bson.M{"$in": modifiers}})
+	}
+	filter := bson.M{"food_id": foodId, "$or": recipeFilter}
+
+	cursor, err := recipeCollection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var recipes []models.Recipe
+	if err = cursor.All(ctx, &recipes); err != nil {
+		return nil, err
+	}
+
+	usage := map[string]float64{}
+	for _, recipe := range recipes {
+		for _, line := range recipe.Lines {
+			usage[line.Ingredient_id] += line.Quantity
+		}
+	}
+	return usage, nil
+}
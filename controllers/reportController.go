@@ -0,0 +1,460 @@
+package controllers
+
+import (
+	"context"
+	"golang-Restaurant-Management-backend/models"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Menu engineering quadrants.
+const (
+	QuadrantStar      = "STAR"      // popular and profitable
+	QuadrantPlowhorse = "PLOWHORSE" // popular, low margin
+	QuadrantPuzzle    = "PUZZLE"    // profitable, rarely ordered
+	QuadrantDog       = "DOG"       // neither
+)
+
+// MarginSummary holds sales, cost and margin figures for a dish, menu or period.
+type MarginSummary struct {
+	Items_sold     int     `json:"items_sold"`
+	Revenue        float64 `json:"revenue"`
+	Cost           float64 `json:"cost"`
+	Margin         float64 `json:"margin"`
+	Margin_percent float64 `json:"margin_percent"`
+}
+
+type DishMargin struct {
+	Food_id   string `json:"food_id,omitempty"`
+	Bundle_id string `json:"bundle_id,omitempty"`
+	Name      string `json:"name"`
+	Menu_id   string `json:"menu_id"`
+	MarginSummary
+	Unit_margin float64 `json:"unit_margin"`
+	Quadrant    string  `json:"quadrant"`
+}
+
+type MenuMargin struct {
+	Menu_id string `json:"menu_id"`
+	Name    string `json:"name"`
+	MarginSummary
+}
+
+type PeriodMargin struct {
+	Period string `json:"period"`
+	MarginSummary
+}
+
+type MarginReport struct {
+	From    time.Time      `json:"from"`
+	To      time.Time      `json:"to"`
+	Totals  MarginSummary  `json:"totals"`
+	Dishes  []DishMargin   `json:"dishes"`
+	Menus   []MenuMargin   `json:"menus"`
+	Periods []PeriodMargin `json:"periods"`
+}
+
+// AdjustmentTotal counts adjustments of one type and what they took off the bills.
+type AdjustmentTotal struct {
+	Count  int     `json:"count"`
+	Amount float64 `json:"amount"`
+}
+
+// StaffAdjustments are the applied adjustments requested by one staff member, by type.
+type StaffAdjustments struct {
+	User_id string                     `json:"user_id"`
+	Name    string                     `json:"name"`
+	Types   map[string]AdjustmentTotal `json:"types"`
+	AdjustmentTotal
+}
+
+type AdjustmentReport struct {
+	From   time.Time                  `json:"from"`
+	To     time.Time                  `json:"to"`
+	Totals map[string]AdjustmentTotal `json:"totals"`
+	Staff  []StaffAdjustments         `json:"staff"`
+}
+
+// FoodPrepTime compares how long a food took in the kitchen with the prep time
+// set on it, and shows the prep time estimates currently use.
+type FoodPrepTime struct {
+	Food_id          string  `json:"food_id"`
+	Name             string  `json:"name"`
+	Prep_minutes     *int    `json:"prep_minutes"`
+	Count            int     `json:"count"`
+	Average_seconds  int     `json:"average_seconds"`
+	Min_seconds      int     `json:"min_seconds"`
+	Max_seconds      int     `json:"max_seconds"`
+	Estimate_seconds int     `json:"estimate_seconds"`
+	Drift_minutes    float64 `json:"drift_minutes"`
+}
+
+type PrepTimeReport struct {
+	From  time.Time      `json:"from"`
+	To    time.Time      `json:"to"`
+	Foods []FoodPrepTime `json:"foods"`
+}
+
+// period formats for $dateToString
+var periodFormats = map[string]string{
+	"day":   "%Y-%m-%d",
+	"week":  "%G-W%V",
+	"month": "%Y-%m",
+}
+
+// GetMarginReport reports revenue, cost and margin per dish, per menu and per
+// period between ?from and ?to (RFC3339 or YYYY-MM-DD, default the last 30 days),
+// and places each dish in its menu engineering quadrant.
+// Bundles count as one dish; their components are not counted separately.
+func GetMarginReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		from, to, ok := reportRange(c)
+		if !ok {
+			return
+		}
+		period := c.DefaultQuery("period", "day")
+		format, ok := periodFormats[period]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "period must be day, week or month"})
+			return
+		}
+		menuId := c.Query("menu_id")
+
+		// Voided lines were never made. Wasted lines were made but not sold, so
+		// they count as cost only; comped lines were sold without revenue.
+		matchStage := bson.D{{"$match", bson.D{
+			{"created_at", bson.D{{"$gte", from}, {"$lt", to}}},
+			{"item_type", bson.D{{"$ne", models.OrderItemTypeComponent}}},
+			{"status", bson.D{{"$ne", models.OrderItemStatusVoided}}},
+		}}}
+		isWasted := bson.D{{"$eq", bson.A{"$status", models.OrderItemStatusWasted}}}
+		isUnpaid := bson.D{{"$or", bson.A{isWasted, bson.D{{"$eq", bson.A{"$comped", true}}}}}}
+		sums := bson.D{
+			{"items_sold", bson.D{{"$sum", bson.D{{"$cond", bson.A{isWasted, 0, 1}}}}}},
+			{"revenue", bson.D{{"$sum", bson.D{{"$cond", bson.A{isUnpaid, 0, bson.D{{"$ifNull", bson.A{"$unit_price", 0}}}}}}}}},
+			{"cost", bson.D{{"$sum", bson.D{{"$ifNull", bson.A{"$unit_cost", 0}}}}}},
+		}
+
+		// Sales per dish.
+		dishGroup := bson.D{{"_id", bson.D{{"food_id", "$food_id"}, {"bundle_id", "$bundle_id"}}}}
+		var dishRows []struct {
+			ID struct {
+				Food_id   *string `bson:"food_id"`
+				Bundle_id *string `bson:"bundle_id"`
+			} `bson:"_id"`
+			Items_sold int     `bson:"items_sold"`
+			Revenue    float64 `bson:"revenue"`
+			Cost       float64 `bson:"cost"`
+		}
+		if err := aggregateInto(ctx, mongo.Pipeline{matchStage, {{"$group", append(dishGroup, sums...)}}}, &dishRows); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while building the margin report"})
+			return
+		}
+
+		report := MarginReport{From: from, To: to, Dishes: []DishMargin{}, Menus: []MenuMargin{}, Periods: []PeriodMargin{}}
+		menus := map[string]*MenuMargin{}
+		for _, row := range dishRows {
+			dish := DishMargin{MarginSummary: summarize(row.Items_sold, row.Revenue, row.Cost)}
+			if row.ID.Bundle_id != nil {
+				var bundle models.Bundle
+				dish.Bundle_id = *row.ID.Bundle_id
+				if err := bundleCollection.FindOne(ctx, bson.M{"bundle_id": dish.Bundle_id}).Decode(&bundle); err == nil {
+					dish.Name, dish.Menu_id = *bundle.Name, *bundle.Menu_id
+				}
+			} else if row.ID.Food_id != nil {
+				var food models.Food
+				dish.Food_id = *row.ID.Food_id
+				if err := foodCollection.FindOne(ctx, bson.M{"food_id": dish.Food_id}).Decode(&food); err == nil {
+					dish.Name, dish.Menu_id = *food.Name, *food.Menu_id
+				}
+			}
+			if menuId != "" && dish.Menu_id != menuId {
+				continue
+			}
+			report.Dishes = append(report.Dishes, dish)
+
+			menu, ok := menus[dish.Menu_id]
+			if !ok {
+				menu = &MenuMargin{Menu_id: dish.Menu_id}
+				menus[dish.Menu_id] = menu
+			}
+			menu.MarginSummary = summarize(menu.Items_sold+dish.Items_sold, menu.Revenue+dish.Revenue, menu.Cost+dish.Cost)
+			report.Totals = summarize(report.Totals.Items_sold+dish.Items_sold, report.Totals.Revenue+dish.Revenue, report.Totals.Cost+dish.Cost)
+		}
+		classifyDishes(report.Dishes)
+
+		for _, menu := range menus {
+			var found models.Menu
+			if err := menuCollection.FindOne(ctx, bson.M{"menu_id": menu.Menu_id}).Decode(&found); err == nil {
+				menu.Name = found.Name
+			}
+			report.Menus = append(report.Menus, *menu)
+		}
+		sort.Slice(report.Menus, func(i, j int) bool { return report.Menus[i].Margin > report.Menus[j].Margin })
+
+		// Sales per period. Filtering by menu needs the food or bundle of each line.
+		pipeline := mongo.Pipeline{matchStage}
+		if menuId != "" {
+			pipeline = append(pipeline,
+				bson.D{{"$lookup", bson.D{{"from", "food"}, {"localField", "food_id"}, {"foreignField", "food_id"}, {"as", "food"}}}},
+				bson.D{{"$lookup", bson.D{{"from", "bundle"}, {"localField", "bundle_id"}, {"foreignField", "bundle_id"}, {"as", "bundle"}}}},
+				bson.D{{"$match", bson.D{{"$or", bson.A{bson.D{{"food.menu_id", menuId}}, bson.D{{"bundle.menu_id", menuId}}}}}}},
+			)
+		}
+		periodGroup := bson.D{{"_id", bson.D{{"$dateToString", bson.D{{"format", format}, {"date", "$created_at"}}}}}}
+		pipeline = append(pipeline, bson.D{{"$group", append(periodGroup, sums...)}}, bson.D{{"$sort", bson.D{{"_id", 1}}}})
+		var periodRows []struct {
+			ID         string  `bson:"_id"`
+			Items_sold int     `bson:"items_sold"`
+			Revenue    float64 `bson:"revenue"`
+			Cost       float64 `bson:"cost"`
+		}
+		if err := aggregateInto(ctx, pipeline, &periodRows); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while building the margin report"})
+			return
+		}
+		for _, row := range periodRows {
+			report.Periods = append(report.Periods, PeriodMargin{Period: row.ID, MarginSummary: summarize(row.Items_sold, row.Revenue, row.Cost)})
+		}
+
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// GetAdjustmentReport reports the voids, waste-voids, comps and refires applied
+// between ?from and ?to per staff member who asked for them, most amount first.
+func GetAdjustmentReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		from, to, ok := reportRange(c)
+		if !ok {
+			return
+		}
+
+		pipeline := mongo.Pipeline{
+			{{"$match", bson.D{
+				{"created_at", bson.D{{"$gte", from}, {"$lt", to}}},
+				{"status", models.AdjustmentApplied},
+			}}},
+			{{"$group", bson.D{
+				{"_id", bson.D{{"user_id", "$requested_by"}, {"type", "$type"}}},
+				{"name", bson.D{{"$last", "$requested_by_name"}}},
+				{"count", bson.D{{"$sum", 1}}},
+				{"amount", bson.D{{"$sum", "$amount"}}},
+			}}},
+		}
+		var rows []struct {
+			ID struct {
+				User_id string `bson:"user_id"`
+				Type    string `bson:"type"`
+			} `bson:"_id"`
+			Name   string  `bson:"name"`
+			Count  int     `bson:"count"`
+			Amount float64 `bson:"amount"`
+		}
+		cursor, err := itemAdjustmentCollection.Aggregate(ctx, pipeline)
+		if err == nil {
+			err = cursor.All(ctx, &rows)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while building the adjustment report"})
+			return
+		}
+
+		report := AdjustmentReport{From: from, To: to, Totals: map[string]AdjustmentTotal{}, Staff: []StaffAdjustments{}}
+		staff := map[string]*StaffAdjustments{}
+		for _, row := range rows {
+			member, ok := staff[row.ID.User_id]
+			if !ok {
+				member = &StaffAdjustments{User_id: row.ID.User_id, Name: row.Name, Types: map[string]AdjustmentTotal{}}
+				staff[row.ID.User_id] = member
+			}
+			member.Types[row.ID.Type] = AdjustmentTotal{Count: row.Count, Amount: toFixed(row.Amount, 2)}
+			member.Count += row.Count
+			member.Amount = toFixed(member.Amount+row.Amount, 2)
+
+			total := report.Totals[row.ID.Type]
+			report.Totals[row.ID.Type] = AdjustmentTotal{Count: total.Count + row.Count, Amount: toFixed(total.Amount+row.Amount, 2)}
+		}
+		for _, member := range staff {
+			report.Staff = append(report.Staff, *member)
+		}
+		sort.Slice(report.Staff, func(i, j int) bool { return report.Staff[i].Amount > report.Staff[j].Amount })
+
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// GetPrepTimeReport reports how long each food took from firing until ready
+// between ?from and ?to (RFC3339 or YYYY-MM-DD, default the last 30 days),
+// against the prep time set on it, most underestimated first.
+func GetPrepTimeReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		from, to, ok := reportRange(c)
+		if !ok {
+			return
+		}
+
+		pipeline := mongo.Pipeline{
+			{{"$match", bson.D{
+				{"ready_at", bson.D{{"$gte", from}, {"$lt", to}}},
+				{"food_id", bson.D{{"$ne", nil}}},
+				{"prep_seconds", bson.D{{"$gt", 0}}},
+			}}},
+			{{"$group", bson.D{
+				{"_id", "$food_id"},
+				{"count", bson.D{{"$sum", 1}}},
+				{"average", bson.D{{"$avg", "$prep_seconds"}}},
+				{"min", bson.D{{"$min", "$prep_seconds"}}},
+				{"max", bson.D{{"$max", "$prep_seconds"}}},
+			}}},
+			{{"$lookup", bson.D{{"from", "food"}, {"localField", "_id"}, {"foreignField", "food_id"}, {"as", "food"}}}},
+			{{"$unwind", bson.D{{"path", "$food"}, {"preserveNullAndEmptyArrays", true}}}},
+		}
+		var rows []struct {
+			Food_id string  `bson:"_id"`
+			Count   int     `bson:"count"`
+			Average float64 `bson:"average"`
+			Min     int     `bson:"min"`
+			Max     int     `bson:"max"`
+			Food    struct {
+				Name         *string `bson:"name"`
+				Prep_minutes *int    `bson:"prep_minutes"`
+			} `bson:"food"`
+		}
+		if err := aggregateInto(ctx, pipeline, &rows); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while building the prep time report"})
+			return
+		}
+		prep, err := loadPrepTimes(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while building the prep time report"})
+			return
+		}
+
+		report := PrepTimeReport{From: from, To: to, Foods: []FoodPrepTime{}}
+		for _, row := range rows {
+			foodId := row.Food_id
+			food := FoodPrepTime{
+				Food_id:          row.Food_id,
+				Name:             stringValue(row.Food.Name),
+				Prep_minutes:     row.Food.Prep_minutes,
+				Count:            row.Count,
+				Average_seconds:  int(row.Average),
+				Min_seconds:      row.Min,
+				Max_seconds:      row.Max,
+				Estimate_seconds: int(prep.of(models.OrderItem{Food_id: &foodId}).Seconds()),
+			}
+			configured := defaultPrepMinutes
+			if row.Food.Prep_minutes != nil {
+				configured = *row.Food.Prep_minutes
+			}
+			food.Drift_minutes = toFixed(row.Average/60-float64(configured), 1)
+			report.Foods = append(report.Foods, food)
+		}
+		sort.Slice(report.Foods, func(i, j int) bool { return report.Foods[i].Drift_minutes > report.Foods[j].Drift_minutes })
+
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// reads ?from and ?to, writing a 400 response and returning false when they can't be parsed.
+func reportRange(c *gin.Context) (time.Time, time.Time, bool) {
+	to := time.Now()
+	from := to.AddDate(0, 0, -30)
+	for _, param := range []struct {
+		name  string
+		value *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		raw := c.Query(param.name)
+		if raw == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			if parsed, err = time.Parse("2006-01-02", raw); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": param.name + " must be RFC3339 or YYYY-MM-DD"})
+				return from, to, false
+			}
+		}
+		*param.value = parsed
+	}
+	return from, to, true
+}
+
+func aggregateInto(ctx context.Context, pipeline mongo.Pipeline, results interface{}) error {
+	cursor, err := orderItemCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	return cursor.All(ctx, results)
+}
+
+func summarize(itemsSold int, revenue float64, cost float64) MarginSummary {
+	summary := MarginSummary{
+		Items_sold: itemsSold,
+		Revenue:    toFixed(revenue, 2),
+		Cost:       toFixed(cost, 2),
+		Margin:     toFixed(revenue-cost, 2),
+	}
+	if revenue > 0 {
+		summary.Margin_percent = toFixed((revenue-cost)/revenue*100, 2)
+	}
+	return summary
+}
+
+// classifyDishes sorts dishes into menu engineering quadrants. A dish is popular
+// when it sells at least 70% of an even share of the items sold, and profitable
+// when its margin per item is at least the average margin per item. Dishes that
+// did not sell are dogs.
+func classifyDishes(dishes []DishMargin) {
+	if len(dishes) == 0 {
+		return
+	}
+	totalSold, totalMargin := 0, 0.0
+	for _, dish := range dishes {
+		totalSold += dish.Items_sold
+		totalMargin += dish.Margin
+	}
+	popularityThreshold := 0.7 * float64(totalSold) / float64(len(dishes))
+	averageMargin := 0.0
+	if totalSold > 0 {
+		averageMargin = totalMargin / float64(totalSold)
+	}
+
+	for i := range dishes {
+		dish := &dishes[i]
+		if dish.Items_sold <= 0 {
+			dish.Unit_margin = 0
+			dish.Quadrant = QuadrantDog
+			continue
+		}
+		dish.Unit_margin = toFixed(dish.Margin/float64(dish.Items_sold), 2)
+		popular := float64(dish.Items_sold) >= popularityThreshold
+		profitable := dish.Margin/float64(dish.Items_sold) >= averageMargin
+		switch {
+		case popular && profitable:
+			dish.Quadrant = QuadrantStar
+		case popular:
+			dish.Quadrant = QuadrantPlowhorse
+		case profitable:
+			dish.Quadrant = QuadrantPuzzle
+		default:
+			dish.Quadrant = QuadrantDog
+		}
+	}
+	sort.Slice(dishes, func(i, j int) bool { return dishes[i].Margin > dishes[j].Margin })
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		itemsSold     int
+		revenue, cost float64
+		want          MarginSummary
+	}{
+		{0, 0, 0, MarginSummary{}},
+		{4, 40, 10, MarginSummary{Items_sold: 4, Revenue: 40, Cost: 10, Margin: 30, Margin_percent: 75}},
+		{3, 10, 3.333, MarginSummary{Items_sold: 3, Revenue: 10, Cost: 3.33, Margin: 6.67, Margin_percent: 66.67}},
+		{1, 5, 8, MarginSummary{Items_sold: 1, Revenue: 5, Cost: 8, Margin: -3, Margin_percent: -60}},
+		{2, 0, 4, MarginSummary{Items_sold: 2, Cost: 4, Margin: -4}},
+	}
+	for _, test := range tests {
+		if got := summarize(test.itemsSold, test.revenue, test.cost); got != test.want {
+			t.Errorf("summarize(%d, %v, %v) = %+v, want %+v", test.itemsSold, test.revenue, test.cost, got, test.want)
+		}
+	}
+}
+
+func TestClassifyDishes(t *testing.T) {
+	dish := func(name string, itemsSold int, margin float64) DishMargin {
+		return DishMargin{Name: name, MarginSummary: MarginSummary{Items_sold: itemsSold, Margin: margin}}
+	}
+	tests := []struct {
+		name   string
+		dishes []DishMargin
+		want   string
+	}{
+		{"none", nil, ""},
+		{
+			"all quadrants",
+			[]DishMargin{dish("star", 40, 400), dish("plowhorse", 40, 80), dish("puzzle", 5, 100), dish("dog", 5, 5)},
+			"star STAR 10, puzzle PUZZLE 20, plowhorse PLOWHORSE 2, dog DOG 1",
+		},
+		{
+			"unsold dish",
+			[]DishMargin{dish("soup", 10, 50), dish("special", 0, 0)},
+			"soup STAR 5, special DOG 0",
+		},
+		{
+			"nothing sold",
+			[]DishMargin{dish("soup", 0, 0)},
+			"soup DOG 0",
+		},
+	}
+	for _, test := range tests {
+		classifyDishes(test.dishes)
+		got := []string{}
+		for _, dish := range test.dishes {
+			got = append(got, fmt.Sprintf("%s %s %g", dish.Name, dish.Quadrant, dish.Unit_margin))
+		}
+		if strings.Join(got, ", ") != test.want {
+			t.Errorf("%s: got %s, want %s", test.name, strings.Join(got, ", "), test.want)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"golang-Restaurant-Management-backend/database"
+	helper "golang-Restaurant-Management-backend/helpers"
+	"golang-Restaurant-Management-backend/models"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var openingHoursCollection *mongo.Collection = database.OpenCollection(database.Client, "opening_hours")
+
+// the prep time of foods that have none.
+const defaultPrepMinutes = 15
+
+// how long before it is due, beyond the longest prep time of its items, a
+// scheduled order goes to the kitchen: SCHEDULED_RELEASE_BUFFER in the
+// environment (e.g. "10m"), 10 minutes by default.
+func releaseBuffer() time.Duration {
+	if value, err := time.ParseDuration(os.Getenv("SCHEDULED_RELEASE_BUFFER")); err == nil && value >= 0 {
+		return value
+	}
+	return 10 * time.Minute
+}
+
+func GetOpeningHours() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		result, err := openingHoursCollection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{"open", 1}}))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while listing opening hours"})
+			return
+		}
+
+		var allOpeningHours []bson.M
+		if err = result.All(ctx, &allOpeningHours); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, allOpeningHours)
+	}
+}
+
+// SetOpeningHours replaces the opening hours with the windows in the body.
+// Without any opening hours, orders can be scheduled at any time.
+func SetOpeningHours() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var openingHours []models.OpeningHours
+		if err := c.BindJSON(&openingHours); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		toInsert := []interface{}{}
+		for i := range openingHours {
+			if validationErr := validate.Struct(openingHours[i]); validationErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+				return
+			}
+			openingHours[i].ID = primitive.NewObjectID()
+			openingHours[i].Opening_hours_id = openingHours[i].ID.Hex()
+			openingHours[i].Created_at = now
+			openingHours[i].Updated_at = now
+			toInsert = append(toInsert, openingHours[i])
+		}
+
+		if _, err := openingHoursCollection.DeleteMany(ctx, bson.M{}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Opening hours were not updated"})
+			return
+		}
+		if len(toInsert) > 0 {
+			if _, err := openingHoursCollection.InsertMany(ctx, toInsert); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Opening hours were not updated"})
+				return
+			}
+		}
+
+		c.JSON(http.StatusOK, openingHours)
+	}
+}
+
+// SetMenuDayparts replaces the dayparts a menu is served in. A menu without
+// dayparts is served whenever the restaurant is open.
+func SetMenuDayparts() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var dayparts []models.Daypart
+		if err := c.BindJSON(&dayparts); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		for _, daypart := range dayparts {
+			if validationErr := validate.Struct(daypart); validationErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+				return
+			}
+		}
+
+		updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		var menu models.Menu
+		after := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		update := bson.M{"$set": bson.M{"dayparts": dayparts, "updated_at": updatedAt}}
+		if err := menuCollection.FindOneAndUpdate(ctx, bson.M{"menu_id": c.Param("menu_id")}, update, after).Decode(&menu); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Menu was not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, menu)
+	}
+}
+
+// GetScheduledOrders lists the orders still waiting for their release to the
+// kitchen, soonest first, wanted between ?from and ?to (RFC3339 or YYYY-MM-DD,
+// default from now on).
+func GetScheduledOrders() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		window := bson.M{"$gte": time.Now()}
+		for _, param := range []struct {
+			name     string
+			operator string
+		}{{"from", "$gte"}, {"to", "$lt"}} {
+			raw := c.Query(param.name)
+			if raw == "" {
+				continue
+			}
+			parsed, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				if parsed, err = time.ParseInLocation("2006-01-02", raw, helper.RESTAURANT_LOCATION); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": param.name + " must be RFC3339 or YYYY-MM-DD"})
+					return
+				}
+			}
+			window[param.operator] = parsed
+		}
+
+		filter := bson.M{"status": models.OrderStatusScheduled, "scheduled_for": window}
+		result, err := orderCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{"scheduled_for", 1}}))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while listing scheduled orders"})
+			return
+		}
+
+		var allOrders []bson.M
+		if err = result.All(ctx, &allOrders); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, allOrders)
+	}
+}
+
+// ReleaseScheduledOrders sends the scheduled orders whose release time has come
+// to the kitchen, firing their first held course. It runs from the scheduler.
+func ReleaseScheduledOrders(ctx context.Context) {
+	filter := bson.M{"status": models.OrderStatusScheduled, "release_at": bson.M{"$lte": time.Now()}}
+	cursor, err := orderCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{"release_at", 1}}))
+	if err != nil {
+		log.Println("listing scheduled orders failed:", err)
+		return
+	}
+	var due []models.Order
+	if err = cursor.All(ctx, &due); err != nil {
+		log.Println("listing scheduled orders failed:", err)
+		return
+	}
+
+	for _, order := range due {
+		if _, err := changeOrderStatus(ctx, nil, order, models.OrderStatusSent, "scheduled release"); err != nil {
+			log.Println("releasing scheduled order", order.Order_id, "failed:", err)
+			continue
+		}
+		if course, ok := nextHeldCourse(ctx, order.Order_id); ok {
+			if _, err := fireCourse(ctx, nil, order.Order_id, course); err != nil {
+				log.Println("firing scheduled order", order.Order_id, "failed:", err)
+			}
+		}
+		publishTickets(ctx, order.Order_id)
+	}
+}
+
+// checks an order can be wanted at t: later than now and, when opening hours
+// are set, while the restaurant is open.
+func checkScheduledFor(ctx context.Context, t time.Time) error {
+	if !t.After(time.Now()) {
+		return fmt.Errorf("scheduled_for must be in the future")
+	}
+
+	var openingHours []models.OpeningHours
+	cursor, err := openingHoursCollection.Find(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	if err = cursor.All(ctx, &openingHours); err != nil {
+		return err
+	}
+	if len(openingHours) == 0 {
+		return nil
+	}
+	local := t.In(helper.RESTAURANT_LOCATION)
+	for _, hours := range openingHours {
+		if models.InWindow(hours.Days, hours.Open, hours.Close, local) {
+			return nil
+		}
+	}
+	return fmt.Errorf("the restaurant is closed at %s", local.Format("Mon 15:04"))
+}
+
+// checks the menus of the order items are served at t: within the menu's
+// start and end dates and, if it has dayparts, in one of them.
+func checkMenusServe(ctx context.Context, orderItems []models.OrderItem, t time.Time) error {
+	menuIds := map[string]string{}
+	for _, orderItem := range orderItems {
+		if orderItem.Food_id != nil {
+			var food models.Food
+			if err := foodCollection.FindOne(ctx, bson.M{"food_id": orderItem.Food_id}).Decode(&food); err == nil && food.Menu_id != nil {
+				menuIds[*food.Menu_id] = stringValue(food.Name)
+			}
+		} else if orderItem.Bundle_id != nil {
+			var bundle models.Bundle
+			if err := bundleCollection.FindOne(ctx, bson.M{"bundle_id": orderItem.Bundle_id}).Decode(&bundle); err == nil && bundle.Menu_id != nil {
+				menuIds[*bundle.Menu_id] = stringValue(bundle.Name)
+			}
+		}
+	}
+
+	local := t.In(helper.RESTAURANT_LOCATION)
+	for menuId, itemName := range menuIds {
+		var menu models.Menu
+		if err := menuCollection.FindOne(ctx, bson.M{"menu_id": menuId}).Decode(&menu); err != nil {
+			continue
+		}
+		if !menuAvailable(menu, t) || !menuServedAt(menu, local) {
+			return fmt.Errorf("%s is not served at %s: the %s menu is not available then", itemName, local.Format("Mon 15:04"), menu.Name)
+		}
+	}
+	return nil
+}
+
+// reports whether one of the menu's dayparts covers t, or the menu has none.
+func menuServedAt(menu models.Menu, t time.Time) bool {
+	if len(menu.Dayparts) == 0 {
+		return true
+	}
+	for _, daypart := range menu.Dayparts {
+		if models.InWindow(daypart.Days, daypart.Start, daypart.End, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// sets when a scheduled order goes to the kitchen: the longest prep time of its
+// held items, plus the release buffer, before the time it is wanted.
+func scheduleRelease(ctx context.Context, orderId string) error {
+	var order models.Order
+	if err := orderCollection.FindOne(ctx, bson.M{"order_id": orderId}).Decode(&order); err != nil {
+		return err
+	}
+	if order.Status != models.OrderStatusScheduled || order.Scheduled_for == nil {
+		return nil
+	}
+
+	var orderItems []models.OrderItem
+	filter := bson.M{"order_id": orderId, "status": itemStatusFilter(models.OrderItemStatusHeld)}
+	cursor, err := orderItemCollection.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if err = cursor.All(ctx, &orderItems); err != nil {
+		return err
+	}
+	longest := 0
+	for _, orderItem := range orderItems {
+		if minutes := itemPrepMinutes(ctx, orderItem); minutes > longest {
+			longest = minutes
+		}
+	}
+
+	releaseAt := order.Scheduled_for.Add(-time.Duration(longest)*time.Minute - releaseBuffer())
+	_, err = orderCollection.UpdateOne(ctx, bson.M{"order_id": orderId}, bson.M{"$set": bson.M{"release_at": releaseAt}})
+	return err
+}
+
+// the minutes the kitchen needs for an order item. Bundle lines need nothing
+// themselves, their components are prepared.
+func itemPrepMinutes(ctx context.Context, orderItem models.OrderItem) int {
+	if orderItem.Food_id == nil {
+		return 0
+	}
+	var food models.Food
+	if err := foodCollection.FindOne(ctx, bson.M{"food_id": orderItem.Food_id}).Decode(&food); err != nil || food.Prep_minutes == nil {
+		return defaultPrepMinutes
+	}
+	return *food.Prep_minutes
+}
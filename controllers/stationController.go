@@ -0,0 +1,293 @@
+package controllers
+
+import (
+	"context"
+	"golang-Restaurant-Management-backend/database"
+	"golang-Restaurant-Management-backend/models"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var stationCollection *mongo.Collection = database.OpenCollection(database.Client, "station")
+var stationRuleCollection *mongo.Collection = database.OpenCollection(database.Client, "station_rule")
+
+func GetStations() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		result, err := stationCollection.Find(ctx, bson.M{})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while listing stations"})
+			return
+		}
+
+		var allStations []bson.M
+		if err = result.All(ctx, &allStations); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, allStations)
+	}
+}
+
+func GetStation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		stationId := c.Param("station_id")
+		var station models.Station
+
+		err := stationCollection.FindOne(ctx, bson.M{"station_id": stationId}).Decode(&station)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while fetching the station"})
+			return
+		}
+
+		c.JSON(http.StatusOK, station)
+	}
+}
+
+func CreateStation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var station models.Station
+
+		if err := c.BindJSON(&station); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		validationErr := validate.Struct(station)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		station.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		station.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		station.ID = primitive.NewObjectID()
+		station.Station_id = station.ID.Hex()
+
+		result, insertErr := stationCollection.InsertOne(ctx, station)
+		if insertErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Station was not created"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+func UpdateStation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var station models.Station
+		stationId := c.Param("station_id")
+
+		if err := c.BindJSON(&station); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var updateObj primitive.D
+		if station.Name != nil {
+			if validationErr := validate.StructPartial(station, "Name"); validationErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+				return
+			}
+			updateObj = append(updateObj, bson.E{"name", station.Name})
+		}
+		if station.Kind != nil {
+			if validationErr := validate.StructPartial(station, "Kind"); validationErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+				return
+			}
+			updateObj = append(updateObj, bson.E{"kind", station.Kind})
+		}
+		if station.Concurrency != nil {
+			if validationErr := validate.StructPartial(station, "Concurrency"); validationErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+				return
+			}
+			updateObj = append(updateObj, bson.E{"concurrency", station.Concurrency})
+		}
+		station.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		updateObj = append(updateObj, bson.E{"updated_at", station.Updated_at})
+
+		result, err := stationCollection.UpdateOne(
+			ctx,
+			bson.M{"station_id": stationId},
+			bson.D{
+				{"$set", updateObj},
+			},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Station update failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// GetStationRules lists routing rules, filtered by ?station_id=, ?food_id= or ?category_id=.
+func GetStationRules() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		filter := bson.M{}
+		for _, field := range []string{"station_id", "food_id", "category_id"} {
+			if value := c.Query(field); value != "" {
+				filter[field] = value
+			}
+		}
+
+		result, err := stationRuleCollection.Find(ctx, filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while listing station rules"})
+			return
+		}
+
+		var allRules []bson.M
+		if err = result.All(ctx, &allRules); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, allRules)
+	}
+}
+
+func CreateStationRule() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var rule models.StationRule
+
+		if err := c.BindJSON(&rule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		validationErr := validate.Struct(rule)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		// The station and the food or category must exist.
+		count, err := stationCollection.CountDocuments(ctx, bson.M{"station_id": rule.Station_id})
+		if err != nil || count == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Station was not found"})
+			return
+		}
+		target := bson.M{"food_id": rule.Food_id}
+		targetCollection := foodCollection
+		if rule.Category_id != nil {
+			target = bson.M{"category_id": rule.Category_id}
+			targetCollection = categoryCollection
+		}
+		count, err = targetCollection.CountDocuments(ctx, target)
+		if err != nil || count == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Food or category was not found"})
+			return
+		}
+
+		// Only one rule may route the same food or category with the same modifier.
+		target["modifier"] = rule.Modifier
+		count, err = stationRuleCollection.CountDocuments(ctx, target)
+		if err != nil || count > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A rule for this food or category and modifier already exists"})
+			return
+		}
+
+		rule.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		rule.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		rule.ID = primitive.NewObjectID()
+		rule.Station_rule_id = rule.ID.Hex()
+
+		result, insertErr := stationRuleCollection.InsertOne(ctx, rule)
+		if insertErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Station rule was not created"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+func DeleteStationRule() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		result, err := stationRuleCollection.DeleteOne(ctx, bson.M{"station_rule_id": c.Param("station_rule_id")})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Station rule was not deleted"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// returns the station preparing a food ordered with the given modifiers, or nil
+// when no rule matches. Rules for one of the modifiers win over plain rules; within
+// each, a rule for the food wins over one for its category, and a category over its parents.
+func routeStation(ctx context.Context, food models.Food, modifiers []string) *string {
+	targets := []bson.M{{"food_id": food.Food_id}}
+	// Walk up the categories, with a depth limit in case of a cycle.
+	for categoryId := stringValue(food.Category_id); categoryId != "" && len(targets) <= 20; {
+		targets = append(targets, bson.M{"category_id": categoryId})
+		var category models.Category
+		if err := categoryCollection.FindOne(ctx, bson.M{"category_id": categoryId}).Decode(&category); err != nil {
+			break
+		}
+		categoryId = stringValue(category.Parent_id)
+	}
+
+	var rules []models.StationRule
+	cursor, err := stationRuleCollection.Find(ctx, bson.M{"$or": targets})
+	if err != nil {
+		log.Println("routing food", food.Food_id, "to a station failed:", err)
+		return nil
+	}
+	if err = cursor.All(ctx, &rules); err != nil {
+		log.Println("routing food", food.Food_id, "to a station failed:", err)
+		return nil
+	}
+
+	matches := func(rule models.StationRule, target bson.M, modifier *string) bool {
+		if food, ok := target["food_id"]; ok && stringValue(rule.Food_id) != food {
+			return false
+		}
+		if category, ok := target["category_id"]; ok && stringValue(rule.Category_id) != category {
+			return false
+		}
+		return stringValue(rule.Modifier) == stringValue(modifier)
+	}
+	for _, modifier := range modifiers {
+		for _, target := range targets {
+			for _, rule := range rules {
+				if matches(rule, target, &modifier) {
+					return rule.Station_id
+				}
+			}
+		}
+	}
+	for _, target := range targets {
+		for _, rule := range rules {
+			if matches(rule, target, nil) {
+				return rule.Station_id
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package controllers
+
+import (
+	"context"
+	"golang-Restaurant-Management-backend/database"
+	"golang-Restaurant-Management-backend/models"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var supplierCollection *mongo.Collection = database.OpenCollection(database.Client, "supplier")
+var supplierItemCollection *mongo.Collection = database.OpenCollection(database.Client, "supplier_item")
+
+func GetSuppliers() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		result, err := supplierCollection.Find(ctx, bson.M{})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while listing suppliers"})
+			return
+		}
+
+		var allSuppliers []bson.M
+		if err = result.All(ctx, &allSuppliers); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, allSuppliers)
+	}
+}
+
+func GetSupplier() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		supplierId := c.Param("supplier_id")
+		var supplier models.Supplier
+
+		err := supplierCollection.FindOne(ctx, bson.M{"supplier_id": supplierId}).Decode(&supplier)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while fetching the supplier"})
+			return
+		}
+
+		c.JSON(http.StatusOK, supplier)
+	}
+}
+
+func CreateSupplier() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var supplier models.Supplier
+
+		if err := c.BindJSON(&supplier); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		validationErr := validate.Struct(supplier)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		supplier.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		supplier.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		supplier.ID = primitive.NewObjectID()
+		supplier.Supplier_id = supplier.ID.Hex()
+
+		result, insertErr := supplierCollection.InsertOne(ctx, supplier)
+		if insertErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Supplier was not created"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+func UpdateSupplier() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var supplier models.Supplier
+		supplierId := c.Param("supplier_id")
+
+		if err := c.BindJSON(&supplier); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var updateObj primitive.D
+
+		if supplier.Name != nil {
+			updateObj = append(updateObj, bson.E{"name", supplier.Name})
+		}
+		if supplier.Contact_name != nil {
+			updateObj = append(updateObj, bson.E{"contact_name", supplier.Contact_name})
+		}
+		if supplier.Email != nil {
+			updateObj = append(updateObj, bson.E{"email", supplier.Email})
+		}
+		if supplier.Phone != nil {
+			updateObj = append(updateObj, bson.E{"phone", supplier.Phone})
+		}
+		if supplier.Lead_time_days != nil {
+			updateObj = append(updateObj, bson.E{"lead_time_days", supplier.Lead_time_days})
+		}
+
+		supplier.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		updateObj = append(updateObj, bson.E{"updated_at", supplier.Updated_at})
+
+		result, err := supplierCollection.UpdateOne(
+			ctx,
+			bson.M{"supplier_id": supplierId},
+			bson.D{
+				{"$set", updateObj},
+			},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Supplier update failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// GetSupplierItems lists the catalog of one supplier.
+func GetSupplierItems() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		supplierId := c.Param("supplier_id")
+
+		result, err := supplierItemCollection.Find(ctx, bson.M{"supplier_id": supplierId})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while listing supplier items"})
+			return
+		}
+
+		var allItems []bson.M
+		if err = result.All(ctx, &allItems); err != nil {
+			log.Fatal(err)
+		}
+
+		c.JSON(http.StatusOK, allItems)
+	}
+}
+
+// CreateSupplierItem adds an ingredient pack to a supplier's catalog.
+func CreateSupplierItem() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var item models.SupplierItem
+
+		if err := c.BindJSON(&item); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		validationErr := validate.Struct(item)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		item.Supplier_id = c.Param("supplier_id")
+		count, err := supplierCollection.CountDocuments(ctx, bson.M{"supplier_id": item.Supplier_id})
+		if err != nil || count == 0 {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Supplier was not found"})
+			return
+		}
+		count, err = ingredientCollection.CountDocuments(ctx, bson.M{"ingredient_id": item.Ingredient_id})
+		if err != nil || count == 0 {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ingredient was not found"})
+			return
+		}
+
+		item.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		item.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		item.ID = primitive.NewObjectID()
+		item.Supplier_item_id = item.ID.Hex()
+
+		var num = toFixed(*item.Pack_cost, 2)
+		item.Pack_cost = &num
+
+		result, insertErr := supplierItemCollection.InsertOne(ctx, item)
+		if insertErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Supplier item was not created"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+func UpdateSupplierItem() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var item models.SupplierItem
+		supplierItemId := c.Param("supplier_item_id")
+
+		if err := c.BindJSON(&item); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var updateObj primitive.D
+
+		if item.Sku != nil {
+			updateObj = append(updateObj, bson.E{"sku", item.Sku})
+		}
+		if item.Pack_size != nil {
+			if *item.Pack_size <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "pack_size must be greater than 0"})
+				return
+			}
+			updateObj = append(updateObj, bson.E{"pack_size", item.Pack_size})
+		}
+		if item.Pack_cost != nil {
+			var num = toFixed(*item.Pack_cost, 2)
+			updateObj = append(updateObj, bson.E{"pack_cost", num})
+		}
+
+		item.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		updateObj = append(updateObj, bson.E{"updated_at", item.Updated_at})
+
+		result, err := supplierItemCollection.UpdateOne(
+			ctx,
+			bson.M{"supplier_item_id": supplierItemId},
+			bson.D{
+				{"$set", updateObj},
+			},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Supplier item update failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
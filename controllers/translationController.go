@@ -0,0 +1,232 @@
+package controllers
+
+import (
+	"context"
+	helper "golang-Restaurant-Management-backend/helpers"
+	"golang-Restaurant-Management-backend/models"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MissingTranslation names a food, menu or category and the fields it lacks in a locale.
+type MissingTranslation struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Fields []string `json:"fields"`
+}
+
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+
+// SetFoodTranslation stores the translation of a food for one locale.
+func SetFoodTranslation() gin.HandlerFunc {
+	return setTranslation(foodCollection, "food_id")
+}
+
+// SetMenuTranslation stores the translation of a menu's name and category for one locale.
+func SetMenuTranslation() gin.HandlerFunc {
+	return setTranslation(menuCollection, "menu_id")
+}
+
+// SetCategoryTranslation stores the translation of a category name for one locale.
+func SetCategoryTranslation() gin.HandlerFunc {
+	return setTranslation(categoryCollection, "category_id")
+}
+
+func setTranslation(collection *mongo.Collection, idField string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		var translation models.Translation
+		locale := helper.NormalizeLocale(c.Param("locale"))
+
+		if !localePattern.MatchString(locale) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "locale must look like en, fr or pt-br"})
+			return
+		}
+		if locale == helper.DEFAULT_LOCALE {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "the default locale is edited through the item itself"})
+			return
+		}
+		if err := c.BindJSON(&translation); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		update := bson.M{"$set": bson.M{"translations." + locale: translation, "updated_at": updatedAt}}
+		result, err := collection.UpdateOne(ctx, bson.M{idField: c.Param(idField)}, update)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Translation was not saved"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item was not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// GetMissingTranslations lists the foods, menus and categories whose text has
+// not been translated to ?locale= yet, optionally for one ?menu_id=.
+func GetMissingTranslations() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		locale := helper.NormalizeLocale(c.Query("locale"))
+
+		if !localePattern.MatchString(locale) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "locale must look like en, fr or pt-br"})
+			return
+		}
+		filter := bson.M{}
+		if menuId := c.Query("menu_id"); menuId != "" {
+			filter["menu_id"] = menuId
+		}
+
+		var foods []models.Food
+		var menus []models.Menu
+		var categories []models.Category
+		for _, query := range []struct {
+			collection *mongo.Collection
+			results    interface{}
+		}{{foodCollection, &foods}, {menuCollection, &menus}, {categoryCollection, &categories}} {
+			cursor, err := query.collection.Find(ctx, filter)
+			if err == nil {
+				err = cursor.All(ctx, query.results)
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occur while listing missing translations"})
+				return
+			}
+		}
+
+		missingFoods := []MissingTranslation{}
+		for _, food := range foods {
+			t := food.Translations[locale]
+			var fields []string
+			if stringValue(food.Name) != "" && t.Name == "" {
+				fields = append(fields, "name")
+			}
+			if stringValue(food.Description) != "" && t.Description == "" {
+				fields = append(fields, "description")
+			}
+			if len(fields) > 0 {
+				missingFoods = append(missingFoods, MissingTranslation{ID: food.Food_id, Name: stringValue(food.Name), Fields: fields})
+			}
+		}
+
+		missingMenus := []MissingTranslation{}
+		for _, menu := range menus {
+			t := menu.Translations[locale]
+			var fields []string
+			if menu.Name != "" && t.Name == "" {
+				fields = append(fields, "name")
+			}
+			if menu.Category != "" && t.Category == "" {
+				fields = append(fields, "category")
+			}
+			if len(fields) > 0 {
+				missingMenus = append(missingMenus, MissingTranslation{ID: menu.Menu_id, Name: menu.Name, Fields: fields})
+			}
+		}
+
+		missingCategories := []MissingTranslation{}
+		for _, category := range categories {
+			if category.Translations[locale].Name == "" {
+				missingCategories = append(missingCategories, MissingTranslation{ID: category.Category_id, Name: stringValue(category.Name), Fields: []string{"name"}})
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"locale":     locale,
+			"foods":      missingFoods,
+			"menus":      missingMenus,
+			"categories": missingCategories,
+		})
+	}
+}
+
+// returns the locales requested by the client and marks the response as varying by language.
+func requestLocales(c *gin.Context) []string {
+	c.Header("Vary", "Accept-Language")
+	return helper.RequestLocales(c)
+}
+
+// replaces the text of a food with its best translation for the requested locales.
+func localizeFood(food *models.Food, locales []string) {
+	locale := helper.PickLocale(locales, func(l string) bool { _, ok := food.Translations[l]; return ok })
+	if locale == "" {
+		return
+	}
+	t := food.Translations[locale]
+	if t.Name != "" {
+		food.Name = &t.Name
+	}
+	if t.Description != "" {
+		food.Description = &t.Description
+	}
+}
+
+func localizeMenu(menu *models.Menu, locales []string) {
+	locale := helper.PickLocale(locales, func(l string) bool { _, ok := menu.Translations[l]; return ok })
+	if locale == "" {
+		return
+	}
+	t := menu.Translations[locale]
+	if t.Name != "" {
+		menu.Name = t.Name
+	}
+	if t.Category != "" {
+		menu.Category = t.Category
+	}
+}
+
+// localizes a menu tree: the menu, every category and every food.
+func localizeTree(tree *MenuTree, locales []string) {
+	localizeMenu(&tree.Menu, locales)
+	localizeCategoryNodes(tree.Categories, locales)
+	for i := range tree.Uncategorized {
+		localizeFood(&tree.Uncategorized[i], locales)
+	}
+}
+
+func localizeCategoryNodes(nodes []*CategoryNode, locales []string) {
+	for _, node := range nodes {
+		locale := helper.PickLocale(locales, func(l string) bool { _, ok := node.Translations[l]; return ok })
+		if text := node.Translations[locale].Name; locale != "" && text != "" {
+			node.Name = text
+		}
+		for i := range node.Foods {
+			localizeFood(&node.Foods[i], locales)
+		}
+		localizeCategoryNodes(node.Children, locales)
+	}
+}
+
+// localizeDoc does the same as localizeFood and localizeMenu for raw documents.
+func localizeDoc(doc bson.M, locales []string) {
+	translations, ok := doc["translations"].(bson.M)
+	if !ok {
+		return
+	}
+	locale := helper.PickLocale(locales, func(l string) bool { _, ok := translations[l]; return ok })
+	if locale == "" {
+		return
+	}
+	t, ok := translations[locale].(bson.M)
+	if !ok {
+		return
+	}
+	for _, field := range []string{"name", "description", "category"} {
+		if text, ok := t[field].(string); ok && text != "" {
+			doc[field] = text
+		}
+	}
+}
@@ -20,6 +20,7 @@ import (
 )
 
 var userCollection *mongo.Collection = database.OpenCollection(database.Client, "user")
+var setupCollection *mongo.Collection = database.OpenCollection(database.Client, "setup")
 
 func GetUsers() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -140,6 +141,13 @@ func SignUp() gin.HandlerFunc {
 		user.ID = primitive.NewObjectID()
 		user.User_id = user.ID.Hex()
 
+		// everyone signs up as staff, except the first user who manages the others.
+		// Of simultaneous first signups only the one that claims the seat becomes manager.
+		user.Role = models.UserRoleStaff
+		if users, err := userCollection.CountDocuments(ctx, bson.M{}); err == nil && users == 0 && claimFirstManager(ctx, user.User_id) {
+			user.Role = models.UserRoleManager
+		}
+
 		// generate token and refresh token (generate all tokens function from Helper)
 		token, refreshToken, _ := helper.GenerateAllTokens(*user.Email, *user.First_name, *user.Last_name, user.User_id)
 		user.Token = &token
@@ -148,6 +156,9 @@ func SignUp() gin.HandlerFunc {
 		// if above are all ok, insert this user to user collection
 		resultInsertionNumber, insertErr := userCollection.InsertOne(ctx, user)
 		if insertErr != nil{
+			if user.Role == models.UserRoleManager {
+				setupCollection.DeleteOne(ctx, bson.M{"_id": firstManagerSeat, "user_id": user.User_id})
+			}
 			msg := fmt.Sprintf("User item was not created")
 			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
 			return
@@ -220,3 +231,75 @@ func VerifyPassword(userPassword string, providePassword string) (bool, string)
 	}
 	return check, msg
 }
+
+// UserRole is the body of a role change.
+// firstManagerSeat is the setup document the first manager's signup inserts.
+// Its fixed _id makes the insert succeed for one signup only.
+const firstManagerSeat = "first_manager"
+
+func claimFirstManager(ctx context.Context, userId string) bool {
+	createdAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	_, err := setupCollection.InsertOne(ctx, bson.M{"_id": firstManagerSeat, "user_id": userId, "created_at": createdAt})
+	return err == nil
+}
+
+// SetRoleByEmail gives the user with the email a role. Deployments whose users
+// signed up before roles existed get their first manager this way, see cmd/setrole.
+func SetRoleByEmail(ctx context.Context, email string, role string) error {
+	if validationErr := validate.Struct(UserRole{Role: role}); validationErr != nil {
+		return validationErr
+	}
+	updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	result, err := userCollection.UpdateOne(ctx, bson.M{"email": email}, bson.M{"$set": bson.M{"role": role, "updated_at": updatedAt}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("no user has the email %s", email)
+	}
+	return nil
+}
+
+type UserRole struct {
+	Role string `json:"role" validate:"required,eq=STAFF|eq=MANAGER"`
+}
+
+// SetUserRole lets a manager make another user staff or manager.
+func SetUserRole() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		if !isManager(ctx, c) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only a manager can change roles"})
+			return
+		}
+
+		var role UserRole
+		if err := c.BindJSON(&role); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if validationErr := validate.Struct(role); validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		result, err := userCollection.UpdateOne(
+			ctx,
+			bson.M{"user_id": c.Param("user_id")},
+			bson.M{"$set": bson.M{"role": role.Role, "updated_at": updatedAt}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "User role update failed"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User was not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
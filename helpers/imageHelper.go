@@ -0,0 +1,122 @@
+package helpers
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif" // register the GIF and PNG decoders for uploads
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // register the WebP decoder for uploads
+)
+
+// MAX_IMAGE_BYTES is the largest image upload accepted, 5 MB unless set in the environment.
+var MAX_IMAGE_BYTES int64 = maxImageBytes()
+
+// MAX_IMAGE_PIXELS caps the decoded size so a small file can't expand into a huge bitmap.
+const MAX_IMAGE_PIXELS = 40_000_000
+
+var ErrImageType = errors.New("image must be a JPEG, PNG, GIF or WebP file")
+var ErrImageTooLarge = errors.New("image is too large")
+
+// ImageVariant is one stored rendition of an uploaded image.
+type ImageVariant struct {
+	Name         string
+	File         string
+	Content_type string
+	Data         []byte
+}
+
+// imageSizes are the widths the resized variants are fitted into.
+var imageSizes = []struct {
+	name  string
+	width int
+}{
+	{"medium", 800},
+	{"thumb", 200},
+}
+
+var imageExtensions = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+	"image/webp": "webp",
+}
+
+func maxImageBytes() int64 {
+	if value, err := strconv.ParseInt(os.Getenv("MAX_IMAGE_BYTES"), 10, 64); err == nil && value > 0 {
+		return value
+	}
+	return 5 << 20
+}
+
+// ProcessImage validates an uploaded image and returns the original plus
+// JPEG and WebP renditions for every size in imageSizes.
+func ProcessImage(data []byte) ([]ImageVariant, error) {
+	if int64(len(data)) > MAX_IMAGE_BYTES {
+		return nil, ErrImageTooLarge
+	}
+	contentType := http.DetectContentType(data)
+	ext, ok := imageExtensions[contentType]
+	if !ok {
+		return nil, ErrImageType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrImageType
+	}
+	if config.Width*config.Height > MAX_IMAGE_PIXELS {
+		return nil, ErrImageTooLarge
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrImageType
+	}
+
+	variants := []ImageVariant{{Name: "original", File: "original." + ext, Content_type: contentType, Data: data}}
+	for _, size := range imageSizes {
+		resized := resizeImage(src, size.width)
+
+		var jpg bytes.Buffer
+		if err := jpeg.Encode(&jpg, resized, &jpeg.Options{Quality: 85}); err != nil {
+			return nil, err
+		}
+		var webp bytes.Buffer
+		if err := nativewebp.Encode(&webp, resized, nil); err != nil {
+			return nil, err
+		}
+		variants = append(variants,
+			ImageVariant{Name: size.name, File: size.name + ".jpg", Content_type: "image/jpeg", Data: jpg.Bytes()},
+			ImageVariant{Name: size.name + "_webp", File: size.name + ".webp", Content_type: "image/webp", Data: webp.Bytes()},
+		)
+	}
+	return variants, nil
+}
+
+// resizeImage fits src into the given width, keeping its aspect ratio and never
+// scaling up. Transparent areas are flattened onto white since JPEG has no alpha.
+func resizeImage(src image.Image, width int) image.Image {
+	bounds := src.Bounds()
+	height := bounds.Dy()
+	if bounds.Dx() > width {
+		height = bounds.Dy() * width / bounds.Dx()
+	} else {
+		width = bounds.Dx()
+	}
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+	return dst
+}
@@ -0,0 +1,87 @@
+package helpers
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DEFAULT_LOCALE is the language the base text of foods and menus is written in.
+var DEFAULT_LOCALE string = defaultLocale()
+
+func defaultLocale() string {
+	if locale := os.Getenv("DEFAULT_LOCALE"); locale != "" {
+		return NormalizeLocale(locale)
+	}
+	return "en"
+}
+
+// NormalizeLocale turns "fr_CA" or "FR-ca" into "fr-ca".
+func NormalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// RequestLocales returns the locales the client asked for, most preferred first:
+// the ?lang= parameter, then the Accept-Language header by quality, then the default locale.
+func RequestLocales(c *gin.Context) []string {
+	locales := []string{}
+	if lang := c.Query("lang"); lang != "" {
+		locales = append(locales, NormalizeLocale(lang))
+	}
+
+	type weighted struct {
+		locale  string
+		quality float64
+	}
+	var accepted []weighted
+	for _, part := range strings.Split(c.GetHeader("Accept-Language"), ",") {
+		fields := strings.Split(part, ";")
+		locale := NormalizeLocale(fields[0])
+		if locale == "" || locale == "*" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range fields[1:] {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if q, err := strconv.ParseFloat(value, 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality > 0 {
+			accepted = append(accepted, weighted{locale, quality})
+		}
+	}
+	sort.SliceStable(accepted, func(i, j int) bool { return accepted[i].quality > accepted[j].quality })
+	for _, a := range accepted {
+		locales = append(locales, a.locale)
+	}
+
+	return append(locales, DEFAULT_LOCALE)
+}
+
+// PickLocale returns the first requested locale that has content, trying "fr"
+// for "fr-ca" as well. It returns "" when the default locale comes first,
+// meaning the base text should be used.
+func PickLocale(locales []string, has func(locale string) bool) string {
+	for _, locale := range locales {
+		if locale == DEFAULT_LOCALE {
+			return ""
+		}
+		if has(locale) {
+			return locale
+		}
+		if base, _, found := strings.Cut(locale, "-"); found {
+			if base == DEFAULT_LOCALE {
+				return ""
+			}
+			if has(base) {
+				return base
+			}
+		}
+	}
+	return ""
+}
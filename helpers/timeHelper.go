@@ -0,0 +1,25 @@
+package helpers
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+// RESTAURANT_LOCATION is the time zone opening hours and menu dayparts are
+// written in, RESTAURANT_TIMEZONE in the environment (e.g. "Europe/Paris"),
+// the server's local time zone by default.
+var RESTAURANT_LOCATION *time.Location = restaurantLocation()
+
+func restaurantLocation() *time.Location {
+	name := os.Getenv("RESTAURANT_TIMEZONE")
+	if name == "" {
+		return time.Local
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		log.Println("unknown RESTAURANT_TIMEZONE", name, "using local time:", err)
+		return time.Local
+	}
+	return location
+}
@@ -4,6 +4,7 @@ package main
 import (
 	"os"
 
+	controllers "golang-Restaurant-Management-backend/controllers"
 	database "golang-Restaurant-Management-backend/database"
 	middleware "golang-Restaurant-Management-backend/middleware"
 	routes "golang-Restaurant-Management-backend/routes"
@@ -30,15 +31,34 @@ func main() {
 
 	// set up routes related to user and use our middleware.
 	routes.UserRoutes(router)
+	routes.ImageRoutes(router)
+	routes.PublicRoutes(router)
 	router.Use(middleware.Authentication())
+	router.Use(middleware.Idempotency())
 
 	// set up another routes
+	routes.UserRoleRoutes(router)
 	routes.FoodRoutes(router)
+	routes.BundleRoutes(router)
 	routes.MenuRoutes(router)
+	routes.MenuVersionRoutes(router)
+	routes.CategoryRoutes(router)
 	routes.TableRoutes(router)
 	routes.OrderRoutes(router)
 	routes.OrderItemRoutes(router)
+	routes.ItemAdjustmentRoutes(router)
+	routes.StationRoutes(router)
+	routes.KitchenRoutes(router)
 	routes.InvoiceRoutes(router)
+	routes.NoteRoutes(router)
+	routes.IngredientRoutes(router)
+	routes.RecipeRoutes(router)
+	routes.SupplierRoutes(router)
+	routes.PurchaseOrderRoutes(router)
+	routes.ReportRoutes(router)
+
+	// publish scheduled menu versions and release scheduled orders in the background
+	controllers.StartScheduler()
 
 	// start the gin server and listen on the 8000 port
 	router.Run(":" + port)
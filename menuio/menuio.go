@@ -0,0 +1,248 @@
+// Package menuio reads and writes menus as flat rows, one food per row, in CSV
+// or JSON. The same format is used by the import and export endpoints and by
+// the menuimport command, so a menu exported from one location can be imported
+// into another.
+package menuio
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Supported formats.
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// CategorySeparator separates the levels of a category path, "Drinks > Wine > Red".
+const CategorySeparator = ">"
+
+// Columns is the CSV header, in the order WriteCSV writes it.
+var Columns = []string{"menu", "menu_category", "category", "sku", "name", "description", "tags", "price", "food_image", "position"}
+
+// Row is one food together with the menu and category it belongs to. Sku is the
+// external key foods are upserted by; Tags are separated by "|" in CSV files.
+type Row struct {
+	Menu          string   `json:"menu"`
+	Menu_category string   `json:"menu_category"`
+	Category      string   `json:"category"`
+	Sku           string   `json:"sku"`
+	Name          string   `json:"name"`
+	Description   string   `json:"description"`
+	Tags          []string `json:"tags"`
+	Price         *float64 `json:"price"`
+	Food_image    string   `json:"food_image"`
+	Position      *int     `json:"position"`
+}
+
+// RowError reports a problem with one row. Rows are numbered from 1, not counting
+// the CSV header; Row is 0 for problems with the file as a whole.
+type RowError struct {
+	Row   int    `json:"row"`
+	Field string `json:"field"`
+	Error string `json:"error"`
+}
+
+// Read parses rows in the given format. Values that cannot be parsed are
+// reported as row errors rather than stopping the whole file.
+func Read(r io.Reader, format string) ([]Row, []RowError) {
+	switch format {
+	case FormatCSV:
+		return ReadCSV(r)
+	case FormatJSON:
+		return ReadJSON(r)
+	}
+	return nil, []RowError{{Field: "format", Error: "format must be csv or json"}}
+}
+
+// Write writes rows in the given format.
+func Write(w io.Writer, format string, rows []Row) error {
+	switch format {
+	case FormatCSV:
+		return WriteCSV(w, rows)
+	case FormatJSON:
+		return WriteJSON(w, rows)
+	}
+	return errors.New("format must be csv or json")
+}
+
+// ReadCSV parses a CSV file whose first line names the columns. Columns may come
+// in any order and unknown columns are rejected.
+func ReadCSV(r io.Reader) ([]Row, []RowError) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, []RowError{{Error: "could not read the CSV header: " + err.Error()}}
+	}
+
+	known := map[string]bool{}
+	for _, column := range Columns {
+		known[column] = true
+	}
+	index := map[string]int{}
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))
+		if !known[column] {
+			return nil, []RowError{{Field: column, Error: "unknown column"}}
+		}
+		index[column] = i
+	}
+
+	var rows []Row
+	var errs []RowError
+	for number := 1; ; number++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			errs = append(errs, RowError{Row: number, Error: err.Error()})
+			if _, ok := err.(*csv.ParseError); ok {
+				continue
+			}
+			break
+		}
+		value := func(column string) string {
+			if i, ok := index[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		row := Row{
+			Menu:          value("menu"),
+			Menu_category: value("menu_category"),
+			Category:      value("category"),
+			Sku:           value("sku"),
+			Name:          value("name"),
+			Description:   value("description"),
+			Food_image:    value("food_image"),
+		}
+		for _, tag := range strings.Split(value("tags"), "|") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				row.Tags = append(row.Tags, tag)
+			}
+		}
+		if price := value("price"); price != "" {
+			num, err := strconv.ParseFloat(price, 64)
+			if err != nil {
+				errs = append(errs, RowError{Row: number, Field: "price", Error: "price is not a number"})
+			} else {
+				row.Price = &num
+			}
+		}
+		if position := value("position"); position != "" {
+			num, err := strconv.Atoi(position)
+			if err != nil {
+				errs = append(errs, RowError{Row: number, Field: "position", Error: "position is not a whole number"})
+			} else {
+				row.Position = &num
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, errs
+}
+
+// ReadJSON parses a JSON array of rows.
+func ReadJSON(r io.Reader) ([]Row, []RowError) {
+	var rows []Row
+	if err := json.NewDecoder(r).Decode(&rows); err != nil {
+		return nil, []RowError{{Error: "could not read the JSON file: " + err.Error()}}
+	}
+	return rows, nil
+}
+
+func WriteCSV(w io.Writer, rows []Row) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(Columns); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		var price, position string
+		if row.Price != nil {
+			price = strconv.FormatFloat(*row.Price, 'f', -1, 64)
+		}
+		if row.Position != nil {
+			position = strconv.Itoa(*row.Position)
+		}
+		record := []string{row.Menu, row.Menu_category, row.Category, row.Sku, row.Name, row.Description,
+			strings.Join(row.Tags, "|"), price, row.Food_image, position}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func WriteJSON(w io.Writer, rows []Row) error {
+	if rows == nil {
+		rows = []Row{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(rows)
+}
+
+// Validate checks the rows on their own, without looking at the database:
+// required fields, value ranges and SKUs used twice in the same file.
+func Validate(rows []Row) []RowError {
+	var errs []RowError
+	seen := map[string]int{}
+	for i, row := range rows {
+		number := i + 1
+		if row.Menu == "" {
+			errs = append(errs, RowError{Row: number, Field: "menu", Error: "menu is required"})
+		}
+		if row.Sku == "" {
+			errs = append(errs, RowError{Row: number, Field: "sku", Error: "sku is required"})
+		} else if first, ok := seen[row.Sku]; ok {
+			errs = append(errs, RowError{Row: number, Field: "sku", Error: fmt.Sprintf("sku is already used on row %d", first)})
+		} else {
+			seen[row.Sku] = number
+		}
+		if n := len([]rune(row.Name)); n < 2 || n > 100 {
+			errs = append(errs, RowError{Row: number, Field: "name", Error: "name must be between 2 and 100 characters"})
+		}
+		if row.Price == nil {
+			errs = append(errs, RowError{Row: number, Field: "price", Error: "price is required"})
+		} else if *row.Price < 0 {
+			errs = append(errs, RowError{Row: number, Field: "price", Error: "price cannot be negative"})
+		}
+		if row.Position != nil && *row.Position < 0 {
+			errs = append(errs, RowError{Row: number, Field: "position", Error: "position cannot be negative"})
+		}
+		for _, name := range SplitCategory(row.Category) {
+			if len([]rune(name)) > 100 {
+				errs = append(errs, RowError{Row: number, Field: "category", Error: "category names must be at most 100 characters"})
+				break
+			}
+		}
+	}
+	return errs
+}
+
+// SplitCategory turns "Drinks > Wine > Red" into its names, skipping empty levels.
+func SplitCategory(path string) []string {
+	var names []string
+	for _, name := range strings.Split(path, CategorySeparator) {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// JoinCategory is the inverse of SplitCategory.
+func JoinCategory(names []string) string {
+	return strings.Join(names, " "+CategorySeparator+" ")
+}
@@ -0,0 +1,152 @@
+package menuio
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func price(p float64) *float64 { return &p }
+
+func position(p int) *int { return &p }
+
+func errorFields(errs []RowError) string {
+	fields := []string{}
+	for _, err := range errs {
+		fields = append(fields, fmt.Sprintf("%d:%s", err.Row, err.Field))
+	}
+	return strings.Join(fields, ", ")
+}
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		wantRows []Row
+		wantErrs string
+	}{
+		{
+			"all columns",
+			"menu,menu_category,category,sku,name,description,tags,price,food_image,position\n" +
+				"Dinner,Evening,Drinks > Wine,W1,House Red,Dry, vegan | house ,7.5,red.png,2\n",
+			[]Row{{Menu: "Dinner", Menu_category: "Evening", Category: "Drinks > Wine", Sku: "W1", Name: "House Red",
+				Description: "Dry", Tags: []string{"vegan", "house"}, Price: price(7.5), Food_image: "red.png", Position: position(2)}},
+			"",
+		},
+		{
+			"columns in any order, header with BOM and case",
+			"\ufeffSKU, Name ,price\nP1,Pizza,12\n",
+			[]Row{{Sku: "P1", Name: "Pizza", Price: price(12)}},
+			"",
+		},
+		{
+			"bad values are row errors",
+			"sku,name,price,position\nP1,Pizza,twelve,1\nP2,Pasta,9,first\n",
+			[]Row{{Sku: "P1", Name: "Pizza", Position: position(1)}, {Sku: "P2", Name: "Pasta", Price: price(9)}},
+			"1:price, 2:position",
+		},
+		{"unknown column", "sku,name,calories\nP1,Pizza,800\n", nil, "0:calories"},
+		{"empty file", "", nil, "0:"},
+	}
+	for _, test := range tests {
+		rows, errs := ReadCSV(strings.NewReader(test.file))
+		if !reflect.DeepEqual(rows, test.wantRows) {
+			t.Errorf("%s: rows %+v, want %+v", test.name, rows, test.wantRows)
+		}
+		if got := errorFields(errs); got != test.wantErrs {
+			t.Errorf("%s: errors %q, want %q", test.name, got, test.wantErrs)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	rows := []Row{
+		{Menu: "Dinner", Menu_category: "Evening", Category: "Drinks > Wine", Sku: "W1", Name: "House Red",
+			Description: "Dry, \"oaky\"", Tags: []string{"vegan", "house"}, Price: price(7.5), Food_image: "red.png", Position: position(2)},
+		{Menu: "Dinner", Sku: "P1", Name: "Pizza", Price: price(12)},
+	}
+	for _, format := range []string{FormatCSV, FormatJSON} {
+		var buf bytes.Buffer
+		if err := Write(&buf, format, rows); err != nil {
+			t.Fatalf("Write(%s): %v", format, err)
+		}
+		got, errs := Read(&buf, format)
+		if len(errs) > 0 {
+			t.Fatalf("Read(%s): %+v", format, errs)
+		}
+		if !reflect.DeepEqual(got, rows) {
+			t.Errorf("%s round trip = %+v, want %+v", format, got, rows)
+		}
+	}
+
+	if _, errs := Read(strings.NewReader(""), "xml"); errorFields(errs) != "0:format" {
+		t.Errorf("Read(xml) errors = %+v", errs)
+	}
+	if err := Write(&bytes.Buffer{}, "xml", rows); err == nil {
+		t.Errorf("Write(xml) did not fail")
+	}
+}
+
+func TestWriteJSONEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(buf.String()); got != "[]" {
+		t.Errorf("WriteJSON(nil) = %s, want []", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := Row{Menu: "Dinner", Sku: "P1", Name: "Pizza", Price: price(12)}
+	with := func(change func(*Row)) Row {
+		row := valid
+		change(&row)
+		return row
+	}
+	tests := []struct {
+		name string
+		rows []Row
+		want string
+	}{
+		{"valid", []Row{valid}, ""},
+		{"missing menu and sku", []Row{with(func(r *Row) { r.Menu, r.Sku = "", "" })}, "1:menu, 1:sku"},
+		{"sku used twice", []Row{valid, with(func(r *Row) { r.Name = "Pasta" })}, "2:sku"},
+		{"short name", []Row{with(func(r *Row) { r.Name = "P" })}, "1:name"},
+		{"long name", []Row{with(func(r *Row) { r.Name = strings.Repeat("é", 101) })}, "1:name"},
+		{"missing price", []Row{with(func(r *Row) { r.Price = nil })}, "1:price"},
+		{"negative price", []Row{with(func(r *Row) { r.Price = price(-1) })}, "1:price"},
+		{"free food", []Row{with(func(r *Row) { r.Price = price(0) })}, ""},
+		{"negative position", []Row{with(func(r *Row) { r.Position = position(-1) })}, "1:position"},
+		{"long category", []Row{with(func(r *Row) { r.Category = "Drinks > " + strings.Repeat("w", 101) })}, "1:category"},
+	}
+	for _, test := range tests {
+		if got := errorFields(Validate(test.rows)); got != test.want {
+			t.Errorf("%s: errors %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestCategoryPath(t *testing.T) {
+	tests := []struct {
+		path  string
+		names []string
+		join  string
+	}{
+		{"", nil, ""},
+		{"Drinks", []string{"Drinks"}, "Drinks"},
+		{"Drinks > Wine > Red", []string{"Drinks", "Wine", "Red"}, "Drinks > Wine > Red"},
+		{" Drinks>>Wine >  ", []string{"Drinks", "Wine"}, "Drinks > Wine"},
+	}
+	for _, test := range tests {
+		names := SplitCategory(test.path)
+		if !reflect.DeepEqual(names, test.names) {
+			t.Errorf("SplitCategory(%q) = %q, want %q", test.path, names, test.names)
+		}
+		if got := JoinCategory(names); got != test.join {
+			t.Errorf("JoinCategory(%q) = %q, want %q", names, got, test.join)
+		}
+	}
+}
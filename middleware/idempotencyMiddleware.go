@@ -0,0 +1,245 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"golang-Restaurant-Management-backend/database"
+	helper "golang-Restaurant-Management-backend/helpers"
+	"golang-Restaurant-Management-backend/models"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const idempotencyHeader = "Idempotency-Key"
+
+// how long a request may run before a retry with its key can take the key
+// over, in case it died without answering. Handlers time out well before.
+const idempotencyLease = 2 * time.Minute
+
+// the largest body hashed for a keyed request: an image upload and its form.
+var maxIdempotentBody = helper.MAX_IMAGE_BYTES + 64<<10
+
+var idempotencyCollection *mongo.Collection = database.OpenCollection(database.Client, "idempotency_key")
+
+var idempotencyIndexOnce sync.Once
+var idempotencyIndexErr error
+
+// Idempotency makes POST requests sent with an Idempotency-Key header safe to
+// retry. The first response for a client and key is stored and replayed for
+// every retry until the key expires; reusing a key for a different request is
+// refused. Clients are the signed-in user, or the caller's address before
+// sign-in. Responses with a 5xx status are not stored, so the request can be retried,
+// nor are requests that panic. Routes answering with credentials must not use it.
+func Idempotency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(idempotencyHeader)
+		if c.Request.Method != http.MethodPost || key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > 255 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": idempotencyHeader + " must be at most 255 characters"})
+			c.Abort()
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := ensureIdempotencyIndexes(ctx); err != nil {
+			log.Println("idempotency keys are not enforced:", err)
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxIdempotentBody))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body is too large"})
+			} else {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Request body could not be read"})
+			}
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		client := c.GetString("uid")
+		if client == "" {
+			client = c.ClientIP()
+		}
+		hash := sha256.New()
+		hash.Write([]byte(c.Request.Method + " " + c.Request.URL.RequestURI() + "\n"))
+		hash.Write(body)
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		record := models.IdempotencyKey{
+			ID:           primitive.NewObjectID(),
+			Client:       client,
+			Key:          key,
+			Method:       c.Request.Method,
+			Path:         c.Request.URL.RequestURI(),
+			Request_hash: hex.EncodeToString(hash.Sum(nil)),
+			State:        models.IdempotencyInProgress,
+			Created_at:   now,
+			Locked_until: now.Add(idempotencyLease),
+			Expires_at:   now.Add(idempotencyWindow()),
+		}
+		record.Idempotency_key_id = record.ID.Hex()
+
+		// Claim the key. If it is taken, answer from the stored request instead.
+		stored, err := claimIdempotencyKey(ctx, record)
+		if err != nil {
+			log.Println("idempotency key was not stored:", err)
+			c.Next()
+			return
+		}
+		if stored != nil {
+			replayIdempotent(c, *stored, record)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		defer func() {
+			if err := recover(); err != nil {
+				releaseIdempotencyKey(record)
+				panic(err)
+			}
+		}()
+		c.Next()
+
+		// Forget failed attempts so that they can be retried with the same key.
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			releaseIdempotencyKey(record)
+			return
+		}
+
+		// The handler may have used up the time of the first context.
+		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		update := bson.M{"$set": bson.M{
+			"state":           models.IdempotencyCompleted,
+			"response_status": c.Writer.Status(),
+			"response_type":   c.Writer.Header().Get("Content-Type"),
+			"response_body":   recorder.body.Bytes(),
+		}}
+		if _, err := idempotencyCollection.UpdateOne(ctx, bson.M{"_id": record.ID}, update); err != nil {
+			log.Println("idempotent response was not stored:", err)
+		}
+	}
+}
+
+// stores the key of a new request. When the client already used the key, the
+// stored request is returned instead, unless it has expired and was only
+// waiting to be removed, or the same request held it past its lease without
+// answering, in which case the key is taken over.
+func claimIdempotencyKey(ctx context.Context, record models.IdempotencyKey) (*models.IdempotencyKey, error) {
+	for {
+		_, err := idempotencyCollection.InsertOne(ctx, record)
+		if err == nil || !mongo.IsDuplicateKeyError(err) {
+			return nil, err
+		}
+		var stored models.IdempotencyKey
+		if err := idempotencyCollection.FindOne(ctx, bson.M{"client": record.Client, "key": record.Key}).Decode(&stored); err != nil {
+			if err == mongo.ErrNoDocuments {
+				continue
+			}
+			return nil, err
+		}
+		now := time.Now()
+		abandoned := stored.State == models.IdempotencyInProgress && stored.Request_hash == record.Request_hash &&
+			stored.Locked_until.Before(now)
+		if stored.Expires_at.After(now) && !abandoned {
+			return &stored, nil
+		}
+		// Only the stored request is removed, not one that took it over meanwhile.
+		if _, err := idempotencyCollection.DeleteOne(ctx, bson.M{"_id": stored.ID, "state": stored.State}); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// removes the key of a request that failed, so that it can be retried.
+func releaseIdempotencyKey(record models.IdempotencyKey) {
+	var ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := idempotencyCollection.DeleteOne(ctx, bson.M{"_id": record.ID}); err != nil {
+		log.Println("failed idempotency key was not released:", err)
+	}
+}
+
+// answers a request whose key was already used: with the stored response when
+// it is the same request, or an error while the first one runs or when the key
+// was used for another request.
+func replayIdempotent(c *gin.Context, stored models.IdempotencyKey, record models.IdempotencyKey) {
+	if stored.Request_hash != record.Request_hash {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "The idempotency key was already used for a different request"})
+		c.Abort()
+		return
+	}
+	if stored.State != models.IdempotencyCompleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "A request with this idempotency key is still being processed"})
+		c.Abort()
+		return
+	}
+
+	c.Header("Idempotent-Replayed", "true")
+	c.Data(stored.Response_status, stored.Response_type, stored.Response_body)
+	c.Abort()
+}
+
+// responseRecorder keeps a copy of the response body as it is written.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
+
+// how long keys are kept, IDEMPOTENCY_KEY_TTL in the environment (e.g. "24h"), 24 hours by default.
+func idempotencyWindow() time.Duration {
+	if value, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_KEY_TTL")); err == nil && value > 0 {
+		return value
+	}
+	return 24 * time.Hour
+}
+
+// creates the unique index on client and key, and the index removing keys
+// once they expire. It runs once per process, on the first keyed request.
+func ensureIdempotencyIndexes(ctx context.Context) error {
+	idempotencyIndexOnce.Do(func() {
+		_, idempotencyIndexErr = idempotencyCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+			{
+				Keys:    bson.D{{"client", 1}, {"key", 1}},
+				Options: options.Index().SetName("client_key").SetUnique(true),
+			},
+			{
+				Keys:    bson.D{{"expires_at", 1}},
+				Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0),
+			},
+		})
+	})
+	return idempotencyIndexErr
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Bundle is a combo or set menu sold at one price and made of slots,
+// e.g. "burger + fries + drink" or a prix-fixe with one slot per course.
+type Bundle struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	Name       *string            `json:"name" validate:"required,min=2,max=100"`
+	Price      *float64           `json:"price" validate:"required,gte=0"`
+	Food_image *string            `json:"food_image"`
+	Slots      []BundleSlot       `json:"slots" validate:"required,min=1,dive"`
+	Created_at time.Time          `json:"created_at"`
+	Updated_at time.Time          `json:"updated_at"`
+	Bundle_id  string             `json:"bundle_id"`
+	Menu_id    *string            `json:"menu_id" validate:"required"`
+}
+
+// BundleSlot lets the guest pick exactly one of the options. On a tasting menu
+// Course sets when the slot is fired; otherwise it follows the bundle line.
+type BundleSlot struct {
+	Name    string         `json:"name" validate:"required"`
+	Course  *int           `json:"course" validate:"omitempty,gte=1"`
+	Options []BundleOption `json:"options" validate:"required,min=1,dive"`
+}
+
+// BundleOption is a food that can fill a slot, with an optional upcharge on top of the bundle price.
+type BundleOption struct {
+	Food_id  string  `json:"food_id" validate:"required"`
+	Upcharge float64 `json:"upcharge" validate:"gte=0"`
+}
+
+// BundleSelection is the guest's choice for one slot when ordering a bundle.
+type BundleSelection struct {
+	Slot    string `json:"slot"`
+	Food_id string `json:"food_id"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Category groups the foods of a menu. Categories nest through Parent_id
+// (Drinks > Wine > Red) and are shown in Position order among their siblings.
+type Category struct {
+	ID           primitive.ObjectID     `bson:"_id"`
+	Name         *string                `json:"name" validate:"required,min=1,max=100"`
+	Translations map[string]Translation `json:"translations"`
+	Menu_id      *string                `json:"menu_id" validate:"required"`
+	Parent_id    *string                `json:"parent_id"`
+	Position     *int                   `json:"position" validate:"omitempty,gte=0"`
+	Created_at   time.Time              `json:"created_at"`
+	Updated_at   time.Time              `json:"updated_at"`
+	Category_id  string                 `json:"category_id"`
+}
@@ -6,12 +6,23 @@ import (
 )
 
 type Food struct {
-	ID         primitive.ObjectID `bson:"_id"`
-	Name       *string            `json:"name" validate:"required, min=2, max=100"`
-	Price      *float64           `json:"price" validate:"required"`
-	Food_image *string            `json:"food_image" validate:"required"`
-	Created_at time.Time          `json:"created_at"`
-	Updated_at time.Time          `json:"updated_at"`
-	Food_id    string            `json:"food_id"`
-	Menu_id    *string            `json:"menu_id" validate:"required"`
+	ID           primitive.ObjectID     `bson:"_id"`
+	Name         *string                `json:"name" validate:"required,min=2,max=100"`
+	Description  *string                `json:"description"`
+	Tags         []string               `json:"tags"`
+	Allergens    []string               `json:"allergens"`
+	Translations map[string]Translation `json:"translations"`
+	Price        *float64               `json:"price" validate:"required"`
+	Nutrition    *Nutrition             `json:"nutrition"`
+	Food_image   *string                `json:"food_image"`
+	Images       map[string]string      `json:"images"`
+	Created_at   time.Time              `json:"created_at"`
+	Updated_at   time.Time              `json:"updated_at"`
+	Food_id      string                 `json:"food_id"`
+	Sku          *string                `json:"sku"`
+	Menu_id      *string                `json:"menu_id" validate:"required"`
+	Category_id  *string                `json:"category_id"`
+	Position     *int                   `json:"position" validate:"omitempty,gte=0"`
+	Prep_minutes *int                   `json:"prep_minutes" validate:"omitempty,gte=0,lte=240"`
+	Off_menu     bool                   `json:"off_menu"`
 }
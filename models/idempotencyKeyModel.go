@@ -0,0 +1,32 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Idempotency key states. A key is IN_PROGRESS while its first request runs,
+// until Locked_until; after that a retry may take it over.
+const (
+	IdempotencyInProgress = "IN_PROGRESS"
+	IdempotencyCompleted  = "COMPLETED"
+)
+
+// IdempotencyKey stores the first response to a POST sent with an
+// Idempotency-Key header, so that retries with the same key get it again.
+type IdempotencyKey struct {
+	ID                 primitive.ObjectID `bson:"_id"`
+	Client             string             `json:"client"`
+	Key                string             `json:"key"`
+	Method             string             `json:"method"`
+	Path               string             `json:"path"`
+	Request_hash       string             `json:"request_hash"`
+	State              string             `json:"state"`
+	Response_status    int                `json:"response_status"`
+	Response_type      string             `json:"response_type"`
+	Response_body      []byte             `json:"response_body"`
+	Created_at         time.Time          `json:"created_at"`
+	Locked_until       time.Time          `json:"locked_until"`
+	Expires_at         time.Time          `json:"expires_at"`
+	Idempotency_key_id string             `json:"idempotency_key_id"`
+}
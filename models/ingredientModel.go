@@ -0,0 +1,63 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Stock adjustment types. DEPLETION is written by the system when order items use a recipe,
+// RETURN when a voided item gives back what it had taken.
+const (
+	StockRestock   = "RESTOCK"
+	StockWastage   = "WASTAGE"
+	StockCount     = "COUNT"
+	StockDepletion = "DEPLETION"
+	StockReturn    = "RETURN"
+)
+
+// Stock alert statuses.
+const (
+	AlertOpen     = "OPEN"
+	AlertResolved = "RESOLVED"
+)
+
+type Ingredient struct {
+	ID                  primitive.ObjectID `bson:"_id"`
+	Name                *string            `json:"name" validate:"required,min=2,max=100"`
+	Unit                *string            `json:"unit" validate:"required"`
+	Stock               *float64           `json:"stock"`
+	Low_stock_threshold *float64           `json:"low_stock_threshold" validate:"omitempty,gte=0"`
+	Par_level           *float64           `json:"par_level" validate:"omitempty,gte=0"`
+	Unit_cost           *float64           `json:"unit_cost"`
+	Created_at          time.Time          `json:"created_at"`
+	Updated_at          time.Time          `json:"updated_at"`
+	Ingredient_id       string             `json:"ingredient_id"`
+}
+
+// StockAdjustment records every change to an ingredient's stock. Quantity is the signed change.
+type StockAdjustment struct {
+	ID            primitive.ObjectID `bson:"_id"`
+	Ingredient_id string             `json:"ingredient_id"`
+	Type          *string            `json:"type" validate:"required,eq=RESTOCK|eq=WASTAGE|eq=COUNT"`
+	Quantity      *float64           `json:"quantity" validate:"required"`
+	Reason        *string            `json:"reason"`
+	Order_item_id *string            `json:"order_item_id"`
+	Unit_cost     *float64           `json:"unit_cost"`
+	Created_by    string             `json:"created_by"`
+	Created_at    time.Time          `json:"created_at"`
+	Adjustment_id string             `json:"adjustment_id"`
+}
+
+// StockAlert is raised when an ingredient falls to or below its low-stock threshold
+// and resolved once it is restocked above it.
+type StockAlert struct {
+	ID              primitive.ObjectID `bson:"_id"`
+	Ingredient_id   string             `json:"ingredient_id"`
+	Ingredient_name string             `json:"ingredient_name"`
+	Stock           float64            `json:"stock"`
+	Threshold       float64            `json:"threshold"`
+	Status          string             `json:"status"`
+	Created_at      time.Time          `json:"created_at"`
+	Resolved_at     *time.Time         `json:"resolved_at"`
+	Alert_id        string             `json:"alert_id"`
+}
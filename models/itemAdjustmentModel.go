@@ -0,0 +1,56 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Adjustments to an order item. A void takes back an item the kitchen has not
+// started, a waste-void one it already made; a comp gives the item away; a
+// refire wastes the item and sends an identical one to the kitchen.
+const (
+	AdjustmentVoid   = "VOID"
+	AdjustmentWaste  = "WASTE"
+	AdjustmentComp   = "COMP"
+	AdjustmentRefire = "REFIRE"
+)
+
+// Adjustment statuses. Adjustments above the approval threshold wait for a manager.
+const (
+	AdjustmentPendingApproval = "PENDING_APPROVAL"
+	AdjustmentApplied         = "APPLIED"
+	AdjustmentRejected        = "REJECTED"
+)
+
+// ReasonCode is a reason staff can pick for the adjustments listed in Actions.
+type ReasonCode struct {
+	ID             primitive.ObjectID `bson:"_id"`
+	Code           *string            `json:"code" validate:"required,min=1,max=30"`
+	Description    *string            `json:"description" validate:"required,max=200"`
+	Actions        []string           `json:"actions" validate:"required,min=1,dive,eq=VOID|eq=WASTE|eq=COMP|eq=REFIRE"`
+	Active         *bool              `json:"active"`
+	Created_at     time.Time          `json:"created_at"`
+	Updated_at     time.Time          `json:"updated_at"`
+	Reason_code_id string             `json:"reason_code_id"`
+}
+
+// ItemAdjustment records a void, waste-void, comp or refire, who asked for it and who approved it.
+type ItemAdjustment struct {
+	ID                   primitive.ObjectID `bson:"_id"`
+	Type                 string             `json:"type"`
+	Status               string             `json:"status"`
+	Order_item_id        string             `json:"order_item_id"`
+	Order_id             string             `json:"order_id"`
+	Reason_code          string             `json:"reason_code"`
+	Reason               string             `json:"reason"`
+	Amount               float64            `json:"amount"`
+	Requested_by         string             `json:"requested_by"`
+	Requested_by_name    string             `json:"requested_by_name"`
+	Approved_by          string             `json:"approved_by"`
+	Approved_by_name     string             `json:"approved_by_name"`
+	Approved_at          *time.Time         `json:"approved_at"`
+	Refire_order_item_id *string            `json:"refire_order_item_id"`
+	Created_at           time.Time          `json:"created_at"`
+	Updated_at           time.Time          `json:"updated_at"`
+	Item_adjustment_id   string             `json:"item_adjustment_id"`
+}
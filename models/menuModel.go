@@ -6,12 +6,16 @@ import (
 )
 
 type Menu struct {
-	ID         primitive.ObjectID `bson:"_id"`
-	Name       string             `json:"name" validate:"required"`
-	Category   string             `json:"category" validate:"required"`
-	Start_Date *time.Time         `json:"start_date"`
-	End_Date   *time.Time         `json:"end_date"`
-	Created_at time.Time          `json:"created_at"`
-	Updated_at time.Time          `json:"updated_at"`
-	Menu_id    string             `json:"food_id"`
+	ID                   primitive.ObjectID     `bson:"_id"`
+	Name                 string                 `json:"name" validate:"required"`
+	Category             string                 `json:"category" validate:"required"`
+	Translations         map[string]Translation `json:"translations"`
+	Start_Date           *time.Time             `json:"start_date"`
+	End_Date             *time.Time             `json:"end_date"`
+	Dayparts             []Daypart              `json:"dayparts" validate:"dive"`
+	Published_version_id *string                `json:"published_version_id"`
+	Published_version    int                    `json:"published_version"`
+	Created_at           time.Time              `json:"created_at"`
+	Updated_at           time.Time              `json:"updated_at"`
+	Menu_id              string                 `json:"food_id"`
 }
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Menu version statuses. A draft is edited freely, a scheduled version is
+// published automatically at Publish_at and the previous published version is
+// archived whenever a new one goes live.
+const (
+	MenuVersionDraft     = "DRAFT"
+	MenuVersionScheduled = "SCHEDULED"
+	MenuVersionPublished = "PUBLISHED"
+	MenuVersionArchived  = "ARCHIVED"
+)
+
+type MenuVersion struct {
+	ID              primitive.ObjectID `bson:"_id"`
+	Menu_id         string             `json:"menu_id"`
+	Version         int                `json:"version"`
+	Status          string             `json:"status"`
+	Items           []MenuVersionItem  `json:"items" validate:"dive"`
+	Publish_at      *time.Time         `json:"publish_at"`
+	Published_at    *time.Time         `json:"published_at"`
+	Created_by      string             `json:"created_by"`
+	Published_by    string             `json:"published_by"`
+	Created_at      time.Time          `json:"created_at"`
+	Updated_at      time.Time          `json:"updated_at"`
+	Menu_version_id string             `json:"menu_version_id"`
+}
+
+// MenuVersionItem is the content a food will have once the version is published.
+type MenuVersionItem struct {
+	Food_id     string   `json:"food_id" validate:"required"`
+	Name        *string  `json:"name" validate:"required,min=2,max=100"`
+	Description *string  `json:"description"`
+	Tags        []string `json:"tags"`
+	Price       *float64 `json:"price" validate:"required,gte=0"`
+	Food_image  *string  `json:"food_image"`
+}
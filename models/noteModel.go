@@ -5,11 +5,41 @@ import (
 	"time"
 )
 
+// What a note can be attached to.
+const (
+	NoteOnOrder     = "ORDER"
+	NoteOnOrderItem = "ORDER_ITEM"
+	NoteOnTable     = "TABLE"
+	NoteOnInvoice   = "INVOICE"
+)
+
+// Note priorities. Allergy notes are always HIGH.
+const (
+	NotePriorityNormal = "NORMAL"
+	NotePriorityHigh   = "HIGH"
+)
+
+// NotePriorityRank is the stored sort key of a priority, lowest first:
+// priorities don't sort by name.
+func NotePriorityRank(priority string) int {
+	if priority == NotePriorityHigh {
+		return 0
+	}
+	return 1
+}
+
 type Note struct {
-	ID         primitive.ObjectID `bson:"_id"`
-	Text       string             `json:"text"`
-	Title      string             `json:"title"`
-	Created_at time.Time          `json:"created_at"`
-	Updated_at time.Time          `json:"updated_at"`
-	Note_id    string             `json:"note_id"`
+	ID            primitive.ObjectID `bson:"_id"`
+	Text          string             `json:"text" validate:"required,max=1000"`
+	Title         string             `json:"title" validate:"max=100"`
+	Entity_type   string             `json:"entity_type"`
+	Entity_id     string             `json:"entity_id"`
+	Priority      string             `json:"priority" validate:"omitempty,eq=NORMAL|eq=HIGH"`
+	Priority_rank int                `json:"priority_rank"`
+	Is_allergy    bool               `json:"is_allergy"`
+	Author_id     string             `json:"author_id"`
+	Author_name   string             `json:"author_name"`
+	Created_at    time.Time          `json:"created_at"`
+	Updated_at    time.Time          `json:"updated_at"`
+	Note_id       string             `json:"note_id"`
 }
@@ -0,0 +1,10 @@
+package models
+
+// Nutrition facts of one portion of a food.
+type Nutrition struct {
+	Calories  float64 `json:"calories" validate:"gte=0"`
+	Protein_g float64 `json:"protein_g" validate:"gte=0"`
+	Carbs_g   float64 `json:"carbs_g" validate:"gte=0"`
+	Fat_g     float64 `json:"fat_g" validate:"gte=0"`
+	Sodium_mg float64 `json:"sodium_mg" validate:"gte=0"`
+}
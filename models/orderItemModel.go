@@ -5,13 +5,62 @@ import (
 	"time"
 )
 
+// Order item types. A bundle is stored as one BUNDLE item carrying the bundle
+// price plus one COMPONENT item per slot, which is what the kitchen prepares.
+const (
+	OrderItemTypeFood      = "FOOD"
+	OrderItemTypeBundle    = "BUNDLE"
+	OrderItemTypeComponent = "COMPONENT"
+)
+
+// Order item statuses, with the statuses each may move to in OrderItemTransitions.
+// New items are HELD and stay off the kitchen screens until they are fired.
+// A ready item can be recalled to the kitchen by moving it back to FIRED.
+// Held items are VOIDED when taken back, items already made are WASTED.
+const (
+	OrderItemStatusHeld   = "HELD"
+	OrderItemStatusFired  = "FIRED"
+	OrderItemStatusReady  = "READY"
+	OrderItemStatusServed = "SERVED"
+	OrderItemStatusVoided = "VOIDED"
+	OrderItemStatusWasted = "WASTED"
+)
+
+var OrderItemTransitions = map[string][]string{
+	OrderItemStatusHeld:   {OrderItemStatusFired, OrderItemStatusVoided},
+	OrderItemStatusFired:  {OrderItemStatusReady, OrderItemStatusWasted},
+	OrderItemStatusReady:  {OrderItemStatusServed, OrderItemStatusFired, OrderItemStatusWasted},
+	OrderItemStatusServed: {OrderItemStatusWasted},
+	OrderItemStatusVoided: {},
+	OrderItemStatusWasted: {},
+}
+
 type OrderItem struct {
-	ID            primitive.ObjectID `bson:"_id"`
-	Quantity      *string            `json:"quantity" validate:"required, eq=S|eq=M|eq=L"`
-	Unit_price    *float64           `json:"unit_price" validate:"required"`
-	Created_at    time.Time          `json:"created_at"`
-	Updated_at    time.Time          `json:"updated_at"`
-	Food_id       *string            `json:"food_id" validate:"required"`
-	Order_item_id string             `json:"order_item_id"`
-	Order_id      string             `json:"order_id" validate:"required"`
+	ID                   primitive.ObjectID `bson:"_id"`
+	Quantity             *string            `json:"quantity" validate:"required,eq=S|eq=M|eq=L"`
+	Unit_price           *float64           `json:"unit_price"`
+	Unit_cost            *float64           `json:"unit_cost"`
+	Nutrition            *Nutrition         `json:"nutrition"`
+	Created_at           time.Time          `json:"created_at"`
+	Updated_at           time.Time          `json:"updated_at"`
+	Food_id              *string            `json:"food_id" validate:"required_without=Bundle_id"`
+	Bundle_id            *string            `json:"bundle_id"`
+	Selections           []BundleSelection  `json:"selections"`
+	Modifiers            []string           `json:"modifiers"`
+	Item_type            string             `json:"item_type"`
+	Status               string             `json:"status"`
+	Course               *int               `json:"course" validate:"omitempty,gte=1"`
+	Fired_at             *time.Time         `json:"fired_at"`
+	Ready_at             *time.Time         `json:"ready_at"`
+	Served_at            *time.Time         `json:"served_at"`
+	Estimated_ready_at   *time.Time         `json:"estimated_ready_at"`
+	Prep_seconds         *int               `json:"prep_seconds"`
+	Comped               bool               `json:"comped"`
+	Refire_of            *string            `json:"refire_of"`
+	History              []OrderEvent       `json:"history"`
+	Parent_order_item_id *string            `json:"parent_order_item_id"`
+	Station_id           *string            `json:"station_id"`
+	Menu_version_id      *string            `json:"menu_version_id"`
+	Order_item_id        string             `json:"order_item_id"`
+	Order_id             string             `json:"order_id" validate:"required"`
 }
@@ -5,11 +5,95 @@ import (
 	"time"
 )
 
+// Order statuses. Orders start OPEN, or SCHEDULED when they are wanted later;
+// OrderTransitions lists where each status may go next.
+const (
+	OrderStatusScheduled = "SCHEDULED"
+	OrderStatusOpen      = "OPEN"
+	OrderStatusSent      = "SENT_TO_KITCHEN"
+	OrderStatusServed    = "SERVED"
+	OrderStatusPaid      = "PAID"
+	OrderStatusClosed    = "CLOSED"
+	OrderStatusCancelled = "CANCELLED"
+	OrderStatusMerged    = "MERGED"
+)
+
+// OrderTransitions maps an order status to the statuses it may move to.
+// A served order goes back to the kitchen when another round is sent. An
+// order merged into another one is MERGED and keeps none of its items. A
+// scheduled order is sent to the kitchen at its release time.
+var OrderTransitions = map[string][]string{
+	OrderStatusScheduled: {OrderStatusSent, OrderStatusCancelled, OrderStatusMerged},
+	OrderStatusOpen:      {OrderStatusSent, OrderStatusPaid, OrderStatusCancelled, OrderStatusMerged},
+	OrderStatusSent:      {OrderStatusServed, OrderStatusPaid, OrderStatusCancelled, OrderStatusMerged},
+	OrderStatusServed:    {OrderStatusSent, OrderStatusPaid, OrderStatusMerged},
+	OrderStatusPaid:      {OrderStatusClosed},
+	OrderStatusClosed:    {},
+	OrderStatusCancelled: {},
+	OrderStatusMerged:    {},
+}
+
+// Order types. Only dine-in orders need a table; orders stored before types
+// existed are dine-in.
+const (
+	OrderTypeDineIn       = "DINE_IN"
+	OrderTypeTakeout      = "TAKEOUT"
+	OrderTypeDelivery     = "DELIVERY"
+	OrderTypeDriveThrough = "DRIVE_THROUGH"
+)
+
+// Order event actions. Moves record the old and new table, merges and item
+// moves the order the items came from and the order they went to.
+const (
+	OrderEventStatus    = "STATUS"
+	OrderEventMoveTable = "MOVE_TABLE"
+	OrderEventMerge     = "MERGE"
+	OrderEventMoveItems = "MOVE_ITEMS"
+)
+
+// OrderEvent records a change made to an order or an order item, and who made it.
+type OrderEvent struct {
+	Action  string    `json:"action"`
+	From    string    `json:"from"`
+	To      string    `json:"to"`
+	Reason  string    `json:"reason,omitempty"`
+	By      string    `json:"by"`
+	By_name string    `json:"by_name"`
+	At      time.Time `json:"at"`
+}
+
 type Order struct {
-	ID         primitive.ObjectID `bson:"_id"`
-	Order_Date time.Time          `json:"order_date" validate:"required"`
-	Created_at time.Time          `json:"created_at"`
-	Updated_at time.Time          `json:"updated_at"`
-	Order_id   string             `json:"order_id"`
-	Table_id   *string            `json:"table_id" validate:"required"`
+	ID                 primitive.ObjectID `bson:"_id"`
+	Order_Date         time.Time          `json:"order_date" validate:"required"`
+	Order_type         string             `json:"order_type" validate:"eq=DINE_IN|eq=TAKEOUT|eq=DELIVERY|eq=DRIVE_THROUGH"`
+	Status             string             `json:"status"`
+	History            []OrderEvent       `json:"history"`
+	Customer_name      *string            `json:"customer_name" validate:"omitempty,max=100"`
+	Customer_phone     *string            `json:"customer_phone" validate:"omitempty,max=30"`
+	Delivery_address   *string            `json:"delivery_address" validate:"omitempty,max=300"`
+	Delivery_fee       *float64           `json:"delivery_fee" validate:"omitempty,gte=0"`
+	Promised_at        *time.Time         `json:"promised_at"`
+	Scheduled_for      *time.Time         `json:"scheduled_for"`
+	Release_at         *time.Time         `json:"release_at"`
+	Estimated_ready_at *time.Time         `json:"estimated_ready_at"`
+	Merged_into        *string            `json:"merged_into"`
+	Created_at         time.Time          `json:"created_at"`
+	Updated_at         time.Time          `json:"updated_at"`
+	Order_id           string             `json:"order_id"`
+	Table_id           *string            `json:"table_id" validate:"required_if=Order_type DINE_IN"`
+}
+
+// CanTransition reports whether an order may move from one status to another.
+func CanTransition(transitions map[string][]string, from string, to string) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// AcceptsItems reports whether items can still be added to or changed on an order in this status.
+func AcceptsItems(status string) bool {
+	return status == OrderStatusScheduled || status == OrderStatusOpen || status == OrderStatusSent || status == OrderStatusServed
 }
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Sources of a price change.
+const (
+	PriceSourceCreate      = "CREATE"
+	PriceSourceUpdate      = "UPDATE"
+	PriceSourceMenuVersion = "MENU_VERSION"
+	PriceSourceImport      = "IMPORT"
+)
+
+// PriceChange is an audit record of a food's price changing. Old_price is nil when the food was created.
+type PriceChange struct {
+	ID              primitive.ObjectID `bson:"_id"`
+	Food_id         string             `json:"food_id"`
+	Old_price       *float64           `json:"old_price"`
+	New_price       float64            `json:"new_price"`
+	Source          string             `json:"source"`
+	Menu_version_id *string            `json:"menu_version_id"`
+	Changed_by      string             `json:"changed_by"`
+	Changed_by_name string             `json:"changed_by_name"`
+	Changed_at      time.Time          `json:"changed_at"`
+	Price_change_id string             `json:"price_change_id"`
+}
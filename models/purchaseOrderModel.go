@@ -0,0 +1,39 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Purchase order statuses. Orders move from DRAFT to SENT to RECEIVED.
+const (
+	PurchaseOrderDraft    = "DRAFT"
+	PurchaseOrderSent     = "SENT"
+	PurchaseOrderReceived = "RECEIVED"
+)
+
+type PurchaseOrder struct {
+	ID                primitive.ObjectID  `bson:"_id"`
+	Supplier_id       *string             `json:"supplier_id" validate:"required"`
+	Status            string              `json:"status"`
+	Lines             []PurchaseOrderLine `json:"lines" validate:"required,min=1,dive"`
+	Total             float64             `json:"total"`
+	Created_by        string              `json:"created_by"`
+	Sent_at           *time.Time          `json:"sent_at"`
+	Received_at       *time.Time          `json:"received_at"`
+	Created_at        time.Time           `json:"created_at"`
+	Updated_at        time.Time           `json:"updated_at"`
+	Purchase_order_id string              `json:"purchase_order_id"`
+}
+
+// PurchaseOrderLine copies pack size and cost from the supplier catalog when the
+// line is added, so later catalog changes don't alter the order.
+type PurchaseOrderLine struct {
+	Supplier_item_id string   `json:"supplier_item_id" validate:"required"`
+	Ingredient_id    string   `json:"ingredient_id"`
+	Packs            float64  `json:"packs" validate:"gt=0"`
+	Pack_size        float64  `json:"pack_size"`
+	Pack_cost        float64  `json:"pack_cost"`
+	Received_packs   *float64 `json:"received_packs"`
+	Stocked          bool     `json:"stocked"`
+}
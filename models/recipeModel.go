@@ -0,0 +1,24 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Recipe lists the ingredients used by one portion of a food. A recipe with a
+// Modifier (e.g. "extra cheese") is used on top of the base recipe when an
+// order item carries that modifier.
+type Recipe struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	Food_id    *string            `json:"food_id" validate:"required"`
+	Modifier   *string            `json:"modifier"`
+	Lines      []RecipeLine       `json:"lines" validate:"required,min=1,dive"`
+	Created_at time.Time          `json:"created_at"`
+	Updated_at time.Time          `json:"updated_at"`
+	Recipe_id  string             `json:"recipe_id"`
+}
+
+type RecipeLine struct {
+	Ingredient_id string  `json:"ingredient_id" validate:"required"`
+	Quantity      float64 `json:"quantity" validate:"gt=0"`
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Daypart is a part of the week a menu is served in, such as breakfast on
+// weekdays. Days are 0 (Sunday) to 6; no days means every day. A daypart that
+// ends before it starts runs past midnight.
+type Daypart struct {
+	Name  string `json:"name" validate:"max=50"`
+	Days  []int  `json:"days" validate:"dive,gte=0,lte=6"`
+	Start string `json:"start" validate:"required,datetime=15:04"`
+	End   string `json:"end" validate:"required,datetime=15:04"`
+}
+
+// OpeningHours is a window in which the restaurant takes orders, on the given days.
+type OpeningHours struct {
+	ID               primitive.ObjectID `bson:"_id"`
+	Days             []int              `json:"days" validate:"dive,gte=0,lte=6"`
+	Open             string             `json:"open" validate:"required,datetime=15:04"`
+	Close            string             `json:"close" validate:"required,datetime=15:04"`
+	Created_at       time.Time          `json:"created_at"`
+	Updated_at       time.Time          `json:"updated_at"`
+	Opening_hours_id string             `json:"opening_hours_id"`
+}
+
+// InWindow reports whether t, in its own time zone, falls in the weekly window
+// running from start to end ("15:04") on the given days. A window ending at or
+// before its start runs past midnight into the next day.
+func InWindow(days []int, start string, end string, t time.Time) bool {
+	from, err := time.Parse("15:04", start)
+	if err != nil {
+		return false
+	}
+	to, err := time.Parse("15:04", end)
+	if err != nil {
+		return false
+	}
+	startMinute := from.Hour()*60 + from.Minute()
+	endMinute := to.Hour()*60 + to.Minute()
+	minute := t.Hour()*60 + t.Minute()
+	weekday := int(t.Weekday())
+
+	if endMinute > startMinute {
+		return onDay(days, weekday) && minute >= startMinute && minute < endMinute
+	}
+	// Overnight: the evening part belongs to today, the early hours to the day before.
+	if minute >= startMinute {
+		return onDay(days, weekday)
+	}
+	return minute < endMinute && onDay(days, (weekday+6)%7)
+}
+
+func onDay(days []int, weekday int) bool {
+	if len(days) == 0 {
+		return true
+	}
+	for _, day := range days {
+		if day == weekday {
+			return true
+		}
+	}
+	return false
+}
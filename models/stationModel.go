@@ -0,0 +1,41 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Kinds of kitchen station.
+const (
+	StationGrill  = "GRILL"
+	StationFry    = "FRY"
+	StationCold   = "COLD"
+	StationBar    = "BAR"
+	StationPastry = "PASTRY"
+)
+
+// Station is a place in the kitchen with its own screen. Concurrency is how
+// many items it prepares at once, one if not set.
+type Station struct {
+	ID          primitive.ObjectID `bson:"_id"`
+	Name        *string            `json:"name" validate:"required,min=1,max=50"`
+	Kind        *string            `json:"kind" validate:"required,eq=GRILL|eq=FRY|eq=COLD|eq=BAR|eq=PASTRY"`
+	Concurrency *int               `json:"concurrency" validate:"omitempty,gte=1,lte=50"`
+	Created_at  time.Time          `json:"created_at"`
+	Updated_at  time.Time          `json:"updated_at"`
+	Station_id  string             `json:"station_id"`
+}
+
+// StationRule sends a food, or every food of a category and its subcategories,
+// to a station. A rule with a Modifier only applies to order items carrying that
+// modifier and overrides the rules without one ("as a cocktail" goes to the bar).
+type StationRule struct {
+	ID              primitive.ObjectID `bson:"_id"`
+	Station_id      *string            `json:"station_id" validate:"required"`
+	Food_id         *string            `json:"food_id" validate:"required_without=Category_id,excluded_with=Category_id"`
+	Category_id     *string            `json:"category_id"`
+	Modifier        *string            `json:"modifier"`
+	Created_at      time.Time          `json:"created_at"`
+	Updated_at      time.Time          `json:"updated_at"`
+	Station_rule_id string             `json:"station_rule_id"`
+}
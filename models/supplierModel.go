@@ -0,0 +1,32 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+type Supplier struct {
+	ID             primitive.ObjectID `bson:"_id"`
+	Name           *string            `json:"name" validate:"required,min=2,max=100"`
+	Contact_name   *string            `json:"contact_name"`
+	Email          *string            `json:"email" validate:"omitempty,email"`
+	Phone          *string            `json:"phone"`
+	Lead_time_days *int               `json:"lead_time_days" validate:"omitempty,gte=0"`
+	Created_at     time.Time          `json:"created_at"`
+	Updated_at     time.Time          `json:"updated_at"`
+	Supplier_id    string             `json:"supplier_id"`
+}
+
+// SupplierItem is an entry of a supplier's catalog: one pack of an ingredient.
+// Pack_size is expressed in the ingredient's unit, e.g. a 5000 g bag of flour.
+type SupplierItem struct {
+	ID               primitive.ObjectID `bson:"_id"`
+	Supplier_id      string             `json:"supplier_id"`
+	Ingredient_id    *string            `json:"ingredient_id" validate:"required"`
+	Sku              *string            `json:"sku"`
+	Pack_size        *float64           `json:"pack_size" validate:"required,gt=0"`
+	Pack_cost        *float64           `json:"pack_cost" validate:"required,gte=0"`
+	Created_at       time.Time          `json:"created_at"`
+	Updated_at       time.Time          `json:"updated_at"`
+	Supplier_item_id string             `json:"supplier_item_id"`
+}
@@ -0,0 +1,9 @@
+package models
+
+// Translation holds the translated text of a food, menu or category for one
+// locale. Empty fields fall back to the text in the default locale.
+type Translation struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Category    string `json:"category,omitempty"`
+}
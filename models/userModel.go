@@ -5,14 +5,21 @@ import (
 	"time"
 )
 
+// User roles. Managers approve voids and comps above the approval threshold.
+const (
+	UserRoleStaff   = "STAFF"
+	UserRoleManager = "MANAGER"
+)
+
 type User struct {
 	ID            primitive.ObjectID `bson:"_id"`
-	First_name    *string            `json:"first_name" validate:"required, min=2, max=100"`
-	Last_name     *string            `json:"last_name" validate:"required, min=2, max=100"`
-	Password      *string            `json:"password" validate:"required, min=6"`
-	Email         *string            `json:"email" validate:"email, required"`
+	First_name    *string            `json:"first_name" validate:"required,min=2,max=100"`
+	Last_name     *string            `json:"last_name" validate:"required,min=2,max=100"`
+	Password      *string            `json:"password" validate:"required,min=6"`
+	Email         *string            `json:"email" validate:"required,email"`
 	Avatar        *string            `json:"avatar"`
 	Phone         *string            `json:"phone" validate:"required"`
+	Role          string             `json:"role"`
 	Token         *string            `json:"token"`
 	Refresh_Token *string            `json:"refresh_token"`
 	Created_at    time.Time          `json:"created_at"`
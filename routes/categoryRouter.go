@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	controller "golang-Restaurant-Management-backend/controllers"
+)
+
+func CategoryRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/categories", controller.GetCategories())
+	incomingRoutes.GET("/categories/:category_id", controller.GetCategory())
+	incomingRoutes.POST("/categories", controller.CreateCategory())
+	incomingRoutes.PATCH("/categories/:category_id", controller.UpdateCategory())
+	incomingRoutes.PUT("/categories/reorder", controller.ReorderCategories())
+	incomingRoutes.PUT("/categories/:category_id/translations/:locale", controller.SetCategoryTranslation())
+}
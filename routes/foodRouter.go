@@ -11,7 +11,13 @@ import(
 // '*gin.Engine' used to represent the web application in this project
 func FoodRoutes(incomingRoutes *gin.Engine){
 	incomingRoutes.GET("/foods", controller.GetFoods()) // Get all food lists
+	incomingRoutes.GET("/foods/search", controller.SearchFoods()) // Search foods by text with filters
 	incomingRoutes.GET("/foods/:food_id", controller.GetFood()) // Get one food's info by specific ID
+	incomingRoutes.GET("/foods/:food_id/cost", controller.GetFoodCost()) // Get theoretical cost and margin of a food item
+	incomingRoutes.GET("/foods/:food_id/price-history", controller.GetPriceHistory()) // Get every price change of a food item
 	incomingRoutes.POST("/foods", controller.CreateFood()) // Create a food item
 	incomingRoutes.PATCH("/foods/:food_id", controller.UpdateFood()) // Update existed food item
+	incomingRoutes.POST("/foods/:food_id/image", controller.UploadFoodImage()) // Upload a food image and generate its thumbnails
+	incomingRoutes.PUT("/foods/reorder", controller.ReorderFoods()) // Set the display position of many food items
+	incomingRoutes.PUT("/foods/:food_id/translations/:locale", controller.SetFoodTranslation()) // Translate a food item
 }
\ No newline at end of file
@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+
+	controller "golang-Restaurant-Management-backend/controllers"
+)
+
+// ImageRoutes are public so menus and receipts can embed the image URLs directly.
+func ImageRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/images/*filepath", controller.ServeImage()) // Serve a stored image with caching headers
+}
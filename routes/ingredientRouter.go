@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	controller "golang-Restaurant-Management-backend/controllers"
+)
+
+func IngredientRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/ingredients", controller.GetIngredients())
+	incomingRoutes.GET("/ingredients/low-stock", controller.GetLowStockIngredients())
+	incomingRoutes.GET("/ingredients/:ingredient_id", controller.GetIngredient())
+	incomingRoutes.POST("/ingredients", controller.CreateIngredient())
+	incomingRoutes.PATCH("/ingredients/:ingredient_id", controller.UpdateIngredient())
+	incomingRoutes.GET("/ingredients/:ingredient_id/adjustments", controller.GetStockAdjustments())
+	incomingRoutes.POST("/ingredients/:ingredient_id/adjustments", controller.AdjustStock())
+	incomingRoutes.GET("/stockAlerts", controller.GetStockAlerts())
+}
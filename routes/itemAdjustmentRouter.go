@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	controller "golang-Restaurant-Management-backend/controllers"
+	"golang-Restaurant-Management-backend/models"
+)
+
+func ItemAdjustmentRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/reasonCodes", controller.GetReasonCodes())
+	incomingRoutes.POST("/reasonCodes", controller.CreateReasonCode())
+	incomingRoutes.PATCH("/reasonCodes/:reason_code_id", controller.UpdateReasonCode())
+	incomingRoutes.GET("/itemAdjustments", controller.GetItemAdjustments())
+	incomingRoutes.POST("/itemAdjustments/:item_adjustment_id/approve", controller.ReviewItemAdjustment(models.AdjustmentApplied))
+	incomingRoutes.POST("/itemAdjustments/:item_adjustment_id/reject", controller.ReviewItemAdjustment(models.AdjustmentRejected))
+}
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	controller "golang-Restaurant-Management-backend/controllers"
+)
+
+func KitchenRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/kds/tickets", controller.GetKitchenTickets())
+	incomingRoutes.GET("/kds/stream", controller.StreamKitchenTickets())
+	incomingRoutes.GET("/kds/expo", controller.GetExpoOrders())
+	incomingRoutes.POST("/kds/items/:orderItem_id/bump", controller.BumpOrderItem())
+	incomingRoutes.POST("/kds/items/:orderItem_id/recall", controller.RecallOrderItem())
+}
@@ -7,7 +7,15 @@ import (
 
 func MenuRoutes(incomingRoutes *gin.Engine) {
 	incomingRoutes.GET("/menus", controller.GetMenus())
+	incomingRoutes.GET("/menus/export", controller.ExportMenus())
 	incomingRoutes.GET("/menus/:menu_id", controller.GetMenu())
+	incomingRoutes.GET("/menus/:menu_id/tree", controller.GetMenuTree())
 	incomingRoutes.POST("/menus", controller.CreateMenu())
+	incomingRoutes.POST("/menus/import", controller.ImportMenus())
 	incomingRoutes.PATCH("/menus/:menu_id", controller.UpdateMenu())
+	incomingRoutes.PUT("/menus/:menu_id/translations/:locale", controller.SetMenuTranslation())
+	incomingRoutes.PUT("/menus/:menu_id/dayparts", controller.SetMenuDayparts())
+	incomingRoutes.GET("/openingHours", controller.GetOpeningHours())
+	incomingRoutes.PUT("/openingHours", controller.SetOpeningHours())
+	incomingRoutes.GET("/translations/missing", controller.GetMissingTranslations())
 }
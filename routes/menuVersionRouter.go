@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	controller "golang-Restaurant-Management-backend/controllers"
+)
+
+func MenuVersionRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/menus/:menu_id/versions", controller.GetMenuVersions())
+	incomingRoutes.POST("/menus/:menu_id/versions", controller.CreateMenuDraft())
+	incomingRoutes.GET("/menuVersions/:menu_version_id", controller.GetMenuVersion())
+	incomingRoutes.PATCH("/menuVersions/:menu_version_id", controller.UpdateMenuDraft())
+	incomingRoutes.POST("/menuVersions/:menu_version_id/publish", controller.PublishMenuVersion())
+}
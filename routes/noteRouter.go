@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	controller "golang-Restaurant-Management-backend/controllers"
+	"golang-Restaurant-Management-backend/models"
+)
+
+func NoteRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/notes/:note_id", controller.GetNote())
+	incomingRoutes.PATCH("/notes/:note_id", controller.UpdateNote())
+	incomingRoutes.GET("/orders/:order_id/notes", controller.GetNotes(models.NoteOnOrder, "order_id"))
+	incomingRoutes.POST("/orders/:order_id/notes", controller.CreateNote(models.NoteOnOrder, "order_id"))
+	incomingRoutes.GET("/orderItems/:orderItem_id/notes", controller.GetNotes(models.NoteOnOrderItem, "orderItem_id"))
+	incomingRoutes.POST("/orderItems/:orderItem_id/notes", controller.CreateNote(models.NoteOnOrderItem, "orderItem_id"))
+	incomingRoutes.GET("/tables/:table_id/notes", controller.GetNotes(models.NoteOnTable, "table_id"))
+	incomingRoutes.POST("/tables/:table_id/notes", controller.CreateNote(models.NoteOnTable, "table_id"))
+	incomingRoutes.GET("/invoices/:invoice_id/notes", controller.GetNotes(models.NoteOnInvoice, "invoice_id"))
+	incomingRoutes.POST("/invoices/:invoice_id/notes", controller.CreateNote(models.NoteOnInvoice, "invoice_id"))
+}
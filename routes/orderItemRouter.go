@@ -3,6 +3,7 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	controller "golang-Restaurant-Management-backend/controllers"
+	"golang-Restaurant-Management-backend/models"
 )
 
 func OrderItemRoutes(incomingRoutes *gin.Engine) {
@@ -11,4 +12,11 @@ func OrderItemRoutes(incomingRoutes *gin.Engine) {
 	incomingRoutes.GET("/orderItems-order/:order_id", controller.GetOrderItemsByOrder())
 	incomingRoutes.POST("/orderItems", controller.CreateOrderItem())
 	incomingRoutes.PATCH("/orderItems/:orderItem_id", controller.UpdateOrderItem())
+	incomingRoutes.POST("/orderItems/:orderItem_id/fire", controller.TransitionOrderItem(models.OrderItemStatusFired))
+	incomingRoutes.POST("/orderItems/:orderItem_id/ready", controller.TransitionOrderItem(models.OrderItemStatusReady))
+	incomingRoutes.POST("/orderItems/:orderItem_id/serve", controller.TransitionOrderItem(models.OrderItemStatusServed))
+	incomingRoutes.POST("/orderItems/:orderItem_id/void", controller.AdjustOrderItem(models.AdjustmentVoid))
+	incomingRoutes.POST("/orderItems/:orderItem_id/waste", controller.AdjustOrderItem(models.AdjustmentWaste))
+	incomingRoutes.POST("/orderItems/:orderItem_id/comp", controller.AdjustOrderItem(models.AdjustmentComp))
+	incomingRoutes.POST("/orderItems/:orderItem_id/refire", controller.AdjustOrderItem(models.AdjustmentRefire))
 }
@@ -3,11 +3,24 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	controller "golang-Restaurant-Management-backend/controllers"
+	"golang-Restaurant-Management-backend/models"
 )
 
 func OrderRoutes(incomingRoutes *gin.Engine) {
 	incomingRoutes.GET("/orders", controller.GetOrders())
+	incomingRoutes.GET("/orders/scheduled", controller.GetScheduledOrders())
 	incomingRoutes.GET("/orders/:order_id", controller.GetOrder())
+	incomingRoutes.GET("/orders/:order_id/estimate", controller.GetOrderEstimate())
 	incomingRoutes.POST("/orders", controller.CreateOrder())
 	incomingRoutes.PATCH("/orders/:order_id", controller.UpdateOrder())
+	incomingRoutes.POST("/orders/:order_id/items", controller.AddOrderItems())
+	incomingRoutes.POST("/orders/:order_id/items/move", controller.MoveItems())
+	incomingRoutes.POST("/orders/:order_id/move", controller.MoveOrderTable())
+	incomingRoutes.POST("/orders/:order_id/merge", controller.MergeOrders())
+	incomingRoutes.POST("/orders/:order_id/send", controller.TransitionOrder(models.OrderStatusSent))
+	incomingRoutes.POST("/orders/:order_id/courses/:course/fire", controller.FireCourse())
+	incomingRoutes.POST("/orders/:order_id/serve", controller.TransitionOrder(models.OrderStatusServed))
+	incomingRoutes.POST("/orders/:order_id/pay", controller.TransitionOrder(models.OrderStatusPaid))
+	incomingRoutes.POST("/orders/:order_id/close", controller.TransitionOrder(models.OrderStatusClosed))
+	incomingRoutes.POST("/orders/:order_id/cancel", controller.TransitionOrder(models.OrderStatusCancelled))
 }
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+
+	controller "golang-Restaurant-Management-backend/controllers"
+)
+
+// PublicRoutes need no token: they serve the read-only menus behind table QR codes.
+func PublicRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/public/menus", controller.GetPublicMenus()) // List the menus available right now
+	incomingRoutes.GET("/public/menus/:menu_id", controller.GetPublicMenu()) // Get a published menu with its categories and foods
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	controller "golang-Restaurant-Management-backend/controllers"
+)
+
+func PurchaseOrderRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/purchaseOrders", controller.GetPurchaseOrders())
+	incomingRoutes.GET("/purchaseOrders/suggested", controller.GetSuggestedReorder())
+	incomingRoutes.GET("/purchaseOrders/:purchase_order_id", controller.GetPurchaseOrder())
+	incomingRoutes.POST("/purchaseOrders", controller.CreatePurchaseOrder())
+	incomingRoutes.PATCH("/purchaseOrders/:purchase_order_id", controller.UpdatePurchaseOrder())
+	incomingRoutes.POST("/purchaseOrders/:purchase_order_id/send", controller.SendPurchaseOrder())
+	incomingRoutes.POST("/purchaseOrders/:purchase_order_id/receive", controller.ReceivePurchaseOrder())
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	controller "golang-Restaurant-Management-backend/controllers"
+)
+
+func RecipeRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/recipes", controller.GetRecipes())
+	incomingRoutes.GET("/recipes/:recipe_id", controller.GetRecipe())
+	incomingRoutes.POST("/recipes", controller.CreateRecipe())
+	incomingRoutes.PATCH("/recipes/:recipe_id", controller.UpdateRecipe())
+}
@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	controller "golang-Restaurant-Management-backend/controllers"
+)
+
+func ReportRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/reports/margins", controller.GetMarginReport())
+	incomingRoutes.GET("/reports/adjustments", controller.GetAdjustmentReport())
+	incomingRoutes.GET("/reports/prepTimes", controller.GetPrepTimeReport())
+}
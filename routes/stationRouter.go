@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	controller "golang-Restaurant-Management-backend/controllers"
+)
+
+func StationRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/stations", controller.GetStations())
+	incomingRoutes.GET("/stations/:station_id", controller.GetStation())
+	incomingRoutes.POST("/stations", controller.CreateStation())
+	incomingRoutes.PATCH("/stations/:station_id", controller.UpdateStation())
+	incomingRoutes.GET("/stationRules", controller.GetStationRules())
+	incomingRoutes.POST("/stationRules", controller.CreateStationRule())
+	incomingRoutes.DELETE("/stationRules/:station_rule_id", controller.DeleteStationRule())
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	controller "golang-Restaurant-Management-backend/controllers"
+)
+
+func SupplierRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/suppliers", controller.GetSuppliers())
+	incomingRoutes.GET("/suppliers/:supplier_id", controller.GetSupplier())
+	incomingRoutes.POST("/suppliers", controller.CreateSupplier())
+	incomingRoutes.PATCH("/suppliers/:supplier_id", controller.UpdateSupplier())
+	incomingRoutes.GET("/suppliers/:supplier_id/items", controller.GetSupplierItems())
+	incomingRoutes.POST("/suppliers/:supplier_id/items", controller.CreateSupplierItem())
+	incomingRoutes.PATCH("/supplierItems/:supplier_item_id", controller.UpdateSupplierItem())
+}
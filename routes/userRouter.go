@@ -3,12 +3,20 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	controller "golang-Restaurant-Management-backend/controllers"
+	"golang-Restaurant-Management-backend/middleware"
 )
 
 func UserRoutes(incomingRoutes *gin.Engine) {
 	// calls the GetUsers function by controller package when the server receives a GET request at URL
 	incomingRoutes.GET("/users", controller.GetUsers())
 	incomingRoutes.GET("/users/:user_id", controller.GetUser())
-	incomingRoutes.POST("/users/signup", controller.SignUp())
+	// signed-out POSTs take idempotency keys per caller address. Login does not:
+	// its response carries tokens, which must not be stored.
+	incomingRoutes.POST("/users/signup", middleware.Idempotency(), controller.SignUp())
 	incomingRoutes.POST("/users/login", controller.Login())
 }
+
+// UserRoleRoutes need a signed-in manager, so they are set up after the authentication middleware.
+func UserRoleRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.PATCH("/users/:user_id/role", controller.SetUserRole())
+}
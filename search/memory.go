@@ -0,0 +1,69 @@
+package search
+
+import (
+	"context"
+	"strings"
+
+	"golang-Restaurant-Management-backend/models"
+)
+
+// MemoryIndex keeps foods and menus in memory. It needs no database, which
+// makes it useful in tests and for checking ranking changes locally.
+type MemoryIndex struct {
+	foods []models.Food
+	menus map[string]models.Menu
+}
+
+// NewMemoryIndex builds an index over the given foods and the menus they belong to.
+func NewMemoryIndex(foods []models.Food, menus []models.Menu) *MemoryIndex {
+	index := &MemoryIndex{menus: map[string]models.Menu{}}
+	for _, menu := range menus {
+		index.menus[menu.Menu_id] = menu
+	}
+	index.foods = append(index.foods, foods...)
+	return index
+}
+
+// Add puts a food into the index, replacing any food with the same food_id.
+func (m *MemoryIndex) Add(food models.Food) {
+	for i := range m.foods {
+		if m.foods[i].Food_id == food.Food_id {
+			m.foods[i] = food
+			return
+		}
+	}
+	m.foods = append(m.foods, food)
+}
+
+func (m *MemoryIndex) SearchFoods(ctx context.Context, query FoodQuery) (FoodResult, error) {
+	terms := Tokenize(query.Text)
+	hits := []FoodHit{}
+
+	for _, food := range m.foods {
+		if food.Off_menu && !query.Include_off_menu {
+			continue
+		}
+		if query.Menu_id != "" && (food.Menu_id == nil || *food.Menu_id != query.Menu_id) {
+			continue
+		}
+		if query.Category != "" {
+			if food.Menu_id == nil || !strings.EqualFold(m.menus[*food.Menu_id].Category, query.Category) {
+				continue
+			}
+		}
+		if !inPriceRange(food, query) {
+			continue
+		}
+
+		score := 0.0
+		if len(terms) > 0 {
+			if score = Score(food, terms); score == 0 {
+				continue
+			}
+		}
+		hits = append(hits, FoodHit{Food: food, Score: score})
+	}
+
+	sortHits(hits, query.Sort)
+	return FoodResult{Total_count: len(hits), Food_items: page(hits, query.Skip, query.Limit)}, nil
+}
@@ -0,0 +1,195 @@
+package search
+
+import (
+	"context"
+	"regexp"
+	"sync"
+
+	"golang-Restaurant-Management-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoIndex searches the food collection through a weighted text index.
+// MongoDB's $text matching is exact on word stems, so when any term of the
+// query matches nothing, a typo say, the typo-tolerant scorer ranks the
+// filtered foods instead.
+type MongoIndex struct {
+	foods     *mongo.Collection
+	menus     *mongo.Collection
+	indexOnce sync.Once
+	indexErr  error
+}
+
+func NewMongoIndex(foods *mongo.Collection, menus *mongo.Collection) *MongoIndex {
+	return &MongoIndex{foods: foods, menus: menus}
+}
+
+// EnsureIndexes creates the text index over name, tags and description.
+// It runs once per process, on the first search.
+func (m *MongoIndex) EnsureIndexes(ctx context.Context) error {
+	m.indexOnce.Do(func() {
+		_, m.indexErr = m.foods.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys: bson.D{{"name", "text"}, {"tags", "text"}, {"description", "text"}},
+			Options: options.Index().
+				SetName("food_text").
+				SetWeights(bson.M{"name": 10, "tags": 5, "description": 1}),
+		})
+	})
+	return m.indexErr
+}
+
+func (m *MongoIndex) SearchFoods(ctx context.Context, query FoodQuery) (FoodResult, error) {
+	if err := m.EnsureIndexes(ctx); err != nil {
+		return FoodResult{}, err
+	}
+
+	filter, err := m.filter(ctx, query)
+	if err != nil {
+		return FoodResult{}, err
+	}
+
+	terms := Tokenize(query.Text)
+	if len(terms) == 0 {
+		return m.find(ctx, filter, query, false)
+	}
+
+	// Use the text index only when it matches every term; otherwise the
+	// terms it misses would get no typo tolerance.
+	for _, term := range terms {
+		matches, err := m.foods.CountDocuments(ctx, textFilter(filter, term), options.Count().SetLimit(1))
+		if err != nil {
+			return FoodResult{}, err
+		}
+		if matches == 0 {
+			return m.fuzzy(ctx, filter, query)
+		}
+	}
+	return m.find(ctx, textFilter(filter, query.Text), query, true)
+}
+
+// textFilter adds a $text search for text to the filter.
+func textFilter(filter bson.M, text string) bson.M {
+	withText := bson.M{"$text": bson.M{"$search": text}}
+	for k, v := range filter {
+		withText[k] = v
+	}
+	return withText
+}
+
+// filter translates the menu, category, price and off-menu filters into a MongoDB filter.
+func (m *MongoIndex) filter(ctx context.Context, query FoodQuery) (bson.M, error) {
+	filter := bson.M{}
+	menuIds := []string{}
+
+	if query.Category != "" {
+		cursor, err := m.menus.Find(ctx, bson.M{"category": bson.M{"$regex": "^" + regexp.QuoteMeta(query.Category) + "$", "$options": "i"}})
+		if err != nil {
+			return nil, err
+		}
+		var menus []models.Menu
+		if err = cursor.All(ctx, &menus); err != nil {
+			return nil, err
+		}
+		for _, menu := range menus {
+			if query.Menu_id == "" || menu.Menu_id == query.Menu_id {
+				menuIds = append(menuIds, menu.Menu_id)
+			}
+		}
+		filter["menu_id"] = bson.M{"$in": menuIds}
+	} else if query.Menu_id != "" {
+		filter["menu_id"] = query.Menu_id
+	}
+
+	price := bson.M{}
+	if query.Min_price != nil {
+		price["$gte"] = *query.Min_price
+	}
+	if query.Max_price != nil {
+		price["$lte"] = *query.Max_price
+	}
+	if len(price) > 0 {
+		filter["price"] = price
+	}
+	if !query.Include_off_menu {
+		filter["off_menu"] = bson.M{"$ne": true}
+	}
+	return filter, nil
+}
+
+// scoredFood is a food document together with the $text relevance score.
+type scoredFood struct {
+	models.Food `bson:",inline"`
+	Score       float64 `bson:"score"`
+}
+
+// find runs the filter in MongoDB, letting the database sort and paginate.
+func (m *MongoIndex) find(ctx context.Context, filter bson.M, query FoodQuery, textScore bool) (FoodResult, error) {
+	total, err := m.foods.CountDocuments(ctx, filter)
+	if err != nil {
+		return FoodResult{}, err
+	}
+
+	opts := options.Find().SetSkip(int64(query.Skip))
+	if query.Limit > 0 {
+		opts.SetLimit(int64(query.Limit))
+	}
+	if textScore {
+		opts.SetProjection(bson.M{"score": bson.M{"$meta": "textScore"}})
+	}
+	switch {
+	case query.Sort == SortPriceAsc:
+		opts.SetSort(bson.D{{"price", 1}, {"name", 1}})
+	case query.Sort == SortPriceDesc:
+		opts.SetSort(bson.D{{"price", -1}, {"name", 1}})
+	case textScore:
+		opts.SetSort(bson.D{{"score", bson.M{"$meta": "textScore"}}, {"name", 1}})
+	default:
+		opts.SetSort(bson.D{{"name", 1}})
+	}
+
+	cursor, err := m.foods.Find(ctx, filter, opts)
+	if err != nil {
+		return FoodResult{}, err
+	}
+	var docs []scoredFood
+	if err = cursor.All(ctx, &docs); err != nil {
+		return FoodResult{}, err
+	}
+
+	hits := make([]FoodHit, 0, len(docs))
+	for _, doc := range docs {
+		hits = append(hits, FoodHit{Food: doc.Food, Score: doc.Score})
+	}
+	return FoodResult{Total_count: int(total), Food_items: hits}, nil
+}
+
+// fuzzy streams the filtered foods and keeps only those the scorer matches,
+// so memory grows with the number of hits rather than with the collection.
+func (m *MongoIndex) fuzzy(ctx context.Context, filter bson.M, query FoodQuery) (FoodResult, error) {
+	terms := Tokenize(query.Text)
+	cursor, err := m.foods.Find(ctx, filter)
+	if err != nil {
+		return FoodResult{}, err
+	}
+	defer cursor.Close(ctx)
+
+	hits := []FoodHit{}
+	for cursor.Next(ctx) {
+		var food models.Food
+		if err := cursor.Decode(&food); err != nil {
+			return FoodResult{}, err
+		}
+		if score := Score(food, terms); score > 0 {
+			hits = append(hits, FoodHit{Food: food, Score: score})
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return FoodResult{}, err
+	}
+
+	sortHits(hits, query.Sort)
+	return FoodResult{Total_count: len(hits), Food_items: page(hits, query.Skip, query.Limit)}, nil
+}
@@ -0,0 +1,222 @@
+// Package search ranks menu items against free-text queries.
+// The scoring here is shared by every Index implementation so results are
+// ranked the same way whether they come from MongoDB or from memory.
+package search
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang-Restaurant-Management-backend/models"
+)
+
+// Supported values for FoodQuery.Sort.
+const (
+	SortRelevance = "relevance"
+	SortPriceAsc  = "price_asc"
+	SortPriceDesc = "price_desc"
+)
+
+// FoodQuery holds the text and filters of a food search request. Foods taken off
+// their menu are left out unless Include_off_menu is set.
+type FoodQuery struct {
+	Text             string
+	Menu_id          string
+	Category         string
+	Min_price        *float64
+	Max_price        *float64
+	Include_off_menu bool
+	Sort             string
+	Skip             int
+	Limit            int
+}
+
+// FoodHit is a single matched food together with its relevance score.
+type FoodHit struct {
+	Food  models.Food `json:"food"`
+	Score float64     `json:"score"`
+}
+
+// FoodResult is one page of hits plus the total number of matches.
+type FoodResult struct {
+	Total_count int       `json:"total_count"`
+	Food_items  []FoodHit `json:"food_items"`
+}
+
+// Index searches foods. MongoIndex is used by the API, MemoryIndex by tests and tooling.
+type Index interface {
+	SearchFoods(ctx context.Context, query FoodQuery) (FoodResult, error)
+}
+
+// ValidSort reports whether s is an accepted sort option (empty means relevance).
+func ValidSort(s string) bool {
+	return s == "" || s == SortRelevance || s == SortPriceAsc || s == SortPriceDesc
+}
+
+// field weights: a hit in the name counts more than one in the tags or description.
+const (
+	nameWeight        = 3.0
+	tagWeight         = 2.0
+	descriptionWeight = 1.0
+)
+
+// Tokenize lower-cases text and splits it into letter/digit words.
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Score returns how well the food matches the query terms, 0 meaning no match.
+func Score(food models.Food, terms []string) float64 {
+	var name, description []string
+	if food.Name != nil {
+		name = Tokenize(*food.Name)
+	}
+	if food.Description != nil {
+		description = Tokenize(*food.Description)
+	}
+	var tags []string
+	for _, tag := range food.Tags {
+		tags = append(tags, Tokenize(tag)...)
+	}
+
+	score := 0.0
+	for _, term := range terms {
+		best := nameWeight * bestMatch(term, name)
+		if s := tagWeight * bestMatch(term, tags); s > best {
+			best = s
+		}
+		if s := descriptionWeight * bestMatch(term, description); s > best {
+			best = s
+		}
+		score += best
+	}
+	return score
+}
+
+// bestMatch scores a term against a list of words: 1 for an exact match,
+// 0.8 for a prefix match and less for matches within the typo tolerance.
+func bestMatch(term string, words []string) float64 {
+	best := 0.0
+	allowed := maxEdits(term)
+	for _, word := range words {
+		if word == term {
+			return 1
+		}
+		if len([]rune(term)) >= 3 && strings.HasPrefix(word, term) && best < 0.8 {
+			best = 0.8
+			continue
+		}
+		if allowed == 0 {
+			continue
+		}
+		if d := editDistance(term, word); d <= allowed {
+			if s := 0.7 - 0.2*float64(d-1); s > best {
+				best = s
+			}
+		}
+	}
+	return best
+}
+
+// maxEdits is the number of typos tolerated for a term: none for short words,
+// one up to six letters and two beyond that.
+func maxEdits(term string) int {
+	switch n := len([]rune(term)); {
+	case n <= 3:
+		return 0
+	case n <= 6:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance is the optimal string alignment distance between a and b,
+// i.e. Levenshtein distance that also counts adjacent swaps as one edit.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+// sortHits orders hits by the requested sort, breaking ties by name.
+func sortHits(hits []FoodHit, order string) {
+	sort.SliceStable(hits, func(i, j int) bool {
+		a, b := hits[i], hits[j]
+		switch order {
+		case SortPriceAsc, SortPriceDesc:
+			pa, pb := price(a.Food), price(b.Food)
+			if pa != pb {
+				if order == SortPriceAsc {
+					return pa < pb
+				}
+				return pa > pb
+			}
+		default:
+			if a.Score != b.Score {
+				return a.Score > b.Score
+			}
+		}
+		return name(a.Food) < name(b.Food)
+	})
+}
+
+// page cuts one page out of the sorted hits.
+func page(hits []FoodHit, skip, limit int) []FoodHit {
+	if skip >= len(hits) {
+		return []FoodHit{}
+	}
+	hits = hits[skip:]
+	if limit > 0 && limit < len(hits) {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+func price(food models.Food) float64 {
+	if food.Price == nil {
+		return 0
+	}
+	return *food.Price
+}
+
+func name(food models.Food) string {
+	if food.Name == nil {
+		return ""
+	}
+	return strings.ToLower(*food.Name)
+}
+
+// inPriceRange checks the food against the optional price bounds of the query.
+func inPriceRange(food models.Food, query FoodQuery) bool {
+	if query.Min_price != nil && price(food) < *query.Min_price {
+		return false
+	}
+	if query.Max_price != nil && price(food) > *query.Max_price {
+		return false
+	}
+	return true
+}
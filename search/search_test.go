@@ -0,0 +1,191 @@
+package search
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"golang-Restaurant-Management-backend/models"
+)
+
+func food(id, name, description string, tags []string, price float64, menuId string) models.Food {
+	return models.Food{Food_id: id, Name: &name, Description: &description, Tags: tags, Price: &price, Menu_id: &menuId}
+}
+
+func hitNames(hits []FoodHit) string {
+	names := []string{}
+	for _, hit := range hits {
+		names = append(names, *hit.Food.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"", ""},
+		{"Pizza", "pizza"},
+		{"Margherita Pizza, 12\"", "margherita|pizza|12"},
+		{"  spicy-chicken  wings ", "spicy|chicken|wings"},
+		{"Crème Brûlée", "crème|brûlée"},
+	}
+	for _, test := range tests {
+		if got := strings.Join(Tokenize(test.text), "|"); got != test.want {
+			t.Errorf("Tokenize(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"pizza", "pizza", 0},
+		{"piza", "pizza", 1},
+		{"pizaz", "pizza", 1},
+		{"ab", "ba", 1},
+		{"kitten", "sitting", 3},
+		{"brûlée", "brulee", 2},
+	}
+	for _, test := range tests {
+		if got := editDistance(test.a, test.b); got != test.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+		if got := editDistance(test.b, test.a); got != test.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", test.b, test.a, got, test.want)
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	margherita := food("1", "Margherita Pizza", "Tomato and mozzarella", []string{"vegetarian"}, 12, "m1")
+	tests := []struct {
+		query string
+		want  float64
+	}{
+		{"pizza", 3},             // exact in the name
+		{"vegetarian", 2},        // exact in the tags
+		{"mozzarella", 1},        // exact in the description
+		{"piz", 2.4},             // prefix of a name word
+		{"margherta", 2.1},       // one typo in a long name word
+		{"piza", 2.1},            // one typo in a short name word
+		{"tomatoe", 0.7},         // one typo in the description
+		{"margherta pizza", 5.1}, // terms add up
+		{"abd", 0},               // short words get no typo tolerance
+		{"burger", 0},
+	}
+	for _, test := range tests {
+		if got := Score(margherita, Tokenize(test.query)); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("Score(%q) = %v, want %v", test.query, got, test.want)
+		}
+	}
+}
+
+func TestSortHits(t *testing.T) {
+	hits := func() []FoodHit {
+		return []FoodHit{
+			{Food: food("1", "Pepperoni Pizza", "", nil, 14, "m1"), Score: 3},
+			{Food: food("2", "Caesar Salad", "", nil, 9, "m1"), Score: 1},
+			{Food: food("3", "Margherita Pizza", "", nil, 12, "m1"), Score: 3},
+			{Food: food("4", "Garlic Bread", "", nil, 9, "m1"), Score: 5},
+		}
+	}
+	tests := []struct {
+		sort string
+		want string
+	}{
+		{"", "Garlic Bread, Margherita Pizza, Pepperoni Pizza, Caesar Salad"},
+		{SortRelevance, "Garlic Bread, Margherita Pizza, Pepperoni Pizza, Caesar Salad"},
+		{SortPriceAsc, "Caesar Salad, Garlic Bread, Margherita Pizza, Pepperoni Pizza"},
+		{SortPriceDesc, "Pepperoni Pizza, Margherita Pizza, Caesar Salad, Garlic Bread"},
+	}
+	for _, test := range tests {
+		sorted := hits()
+		sortHits(sorted, test.sort)
+		if got := hitNames(sorted); got != test.want {
+			t.Errorf("sortHits(%q) = %s, want %s", test.sort, got, test.want)
+		}
+	}
+}
+
+func TestPage(t *testing.T) {
+	hits := []FoodHit{}
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		hits = append(hits, FoodHit{Food: food(name, name, "", nil, 1, "m1")})
+	}
+	tests := []struct {
+		skip, limit int
+		want        string
+	}{
+		{0, 2, "a, b"},
+		{2, 2, "c, d"},
+		{4, 10, "e"},
+		{5, 2, ""},
+		{9, 2, ""},
+		{1, 0, "b, c, d, e"},
+	}
+	for _, test := range tests {
+		if got := hitNames(page(hits, test.skip, test.limit)); got != test.want {
+			t.Errorf("page(%d, %d) = %s, want %s", test.skip, test.limit, got, test.want)
+		}
+	}
+}
+
+func TestMemoryIndexSearchFoods(t *testing.T) {
+	index := NewMemoryIndex(
+		[]models.Food{
+			food("1", "Margherita Pizza", "Tomato and mozzarella", []string{"vegetarian"}, 12, "m1"),
+			food("2", "Pepperoni Pizza", "Tomato, mozzarella and pepperoni", nil, 14, "m1"),
+			food("3", "Caesar Salad", "Romaine and parmesan", nil, 9, "m2"),
+		},
+		[]models.Menu{{Menu_id: "m1", Category: "Dinner"}, {Menu_id: "m2", Category: "Lunch"}},
+	)
+	maxPrice := 13.0
+	tests := []struct {
+		name      string
+		query     FoodQuery
+		wantTotal int
+		want      string
+	}{
+		{"typo ranked with an exact term", FoodQuery{Text: "margherta pizza"}, 2, "Margherita Pizza, Pepperoni Pizza"},
+		{"price bound", FoodQuery{Text: "pizza", Max_price: &maxPrice}, 1, "Margherita Pizza"},
+		{"menu category, any case", FoodQuery{Category: "lunch"}, 1, "Caesar Salad"},
+		{"menu filter", FoodQuery{Text: "salad", Menu_id: "m1"}, 0, ""},
+		{"no text, by price", FoodQuery{Sort: SortPriceDesc}, 3, "Pepperoni Pizza, Margherita Pizza, Caesar Salad"},
+		{"paged", FoodQuery{Sort: SortPriceAsc, Skip: 1, Limit: 1}, 3, "Margherita Pizza"},
+	}
+	for _, test := range tests {
+		result, err := index.SearchFoods(context.Background(), test.query)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if result.Total_count != test.wantTotal {
+			t.Errorf("%s: total %d, want %d", test.name, result.Total_count, test.wantTotal)
+		}
+		if got := hitNames(result.Food_items); got != test.want {
+			t.Errorf("%s: got %s, want %s", test.name, got, test.want)
+		}
+	}
+
+	offMenu := food("4", "Hawaiian Pizza", "Tomato and pineapple", nil, 11, "m1")
+	offMenu.Off_menu = true
+	index.Add(offMenu)
+	for _, include := range []bool{false, true} {
+		result, _ := index.SearchFoods(context.Background(), FoodQuery{Text: "hawaiian", Include_off_menu: include})
+		if found := result.Total_count == 1; found != include {
+			t.Errorf("off-menu food found %v with Include_off_menu %v", found, include)
+		}
+	}
+
+	index.Add(food("1", "Margherita Pizza", "Tomato and mozzarella", nil, 20, "m1"))
+	result, _ := index.SearchFoods(context.Background(), FoodQuery{Text: "pizza", Max_price: &maxPrice})
+	if result.Total_count != 0 {
+		t.Errorf("Add did not replace the food: %s", hitNames(result.Food_items))
+	}
+}
@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStore keeps files in a directory on the local filesystem.
+type LocalStore struct {
+	root string
+}
+
+func NewLocalStore(root string) *LocalStore {
+	return &LocalStore{root: root}
+}
+
+func (l *LocalStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	name, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so readers never see a partial file.
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+func (l *LocalStore) Open(ctx context.Context, key string) (io.ReadCloser, Object, error) {
+	name, err := l.path(key)
+	if err != nil {
+		return nil, Object{}, err
+	}
+	file, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, Object{}, ErrNotFound
+	}
+	if err != nil {
+		return nil, Object{}, err
+	}
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		file.Close()
+		return nil, Object{}, ErrNotFound
+	}
+	return file, localObject(key, info), nil
+}
+
+func (l *LocalStore) Stat(ctx context.Context, key string) (Object, error) {
+	name, err := l.path(key)
+	if err != nil {
+		return Object{}, err
+	}
+	info, err := os.Stat(name)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && info.IsDir()) {
+		return Object{}, ErrNotFound
+	}
+	if err != nil {
+		return Object{}, err
+	}
+	return localObject(key, info), nil
+}
+
+func localObject(key string, info fs.FileInfo) Object {
+	object := Object{
+		Content_type: mime.TypeByExtension(path.Ext(key)),
+		Size:         info.Size(),
+		Modified:     info.ModTime(),
+	}
+	if object.Content_type == "" {
+		object.Content_type = "application/octet-stream"
+	}
+	return object
+}
+
+// path maps a key to a file under the root, refusing keys that would escape it.
+func (l *LocalStore) path(key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if clean == "/" || strings.Contains(key, "..") {
+		return "", errors.New("storage: invalid key " + key)
+	}
+	return filepath.Join(l.root, filepath.FromSlash(clean)), nil
+}
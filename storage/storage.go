@@ -0,0 +1,57 @@
+// Package storage keeps uploaded files such as food images. Files are stored
+// through the Store interface, on the local filesystem by default or in an
+// S3-compatible bucket when STORAGE_DRIVER=s3.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// ErrNotFound is returned by Open and Stat when no file exists under the key.
+var ErrNotFound = errors.New("storage: file not found")
+
+// Object describes a stored file.
+type Object struct {
+	Content_type string
+	Size         int64
+	Modified     time.Time
+}
+
+type Store interface {
+	// Put stores data under key, replacing any existing file.
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Open returns the content of the file stored under key. The caller closes it.
+	Open(ctx context.Context, key string) (io.ReadCloser, Object, error)
+	// Stat describes the file stored under key without reading it.
+	Stat(ctx context.Context, key string) (Object, error)
+}
+
+// NewFromEnv builds the Store selected by STORAGE_DRIVER ("local" or "s3").
+func NewFromEnv() (Store, error) {
+	switch os.Getenv("STORAGE_DRIVER") {
+	case "", "local":
+		dir := os.Getenv("STORAGE_LOCAL_DIR")
+		if dir == "" {
+			dir = "uploads"
+		}
+		return NewLocalStore(dir), nil
+	case "s3":
+		region := os.Getenv("S3_REGION")
+		if region == "" {
+			region = "us-east-1"
+		}
+		return NewS3Store(S3Config{
+			Endpoint:          os.Getenv("S3_ENDPOINT"),
+			Bucket:            os.Getenv("S3_BUCKET"),
+			Region:            region,
+			Access_key_id:     os.Getenv("S3_ACCESS_KEY_ID"),
+			Secret_access_key: os.Getenv("S3_SECRET_ACCESS_KEY"),
+		})
+	default:
+		return nil, errors.New("storage: unknown STORAGE_DRIVER " + os.Getenv("STORAGE_DRIVER"))
+	}
+}